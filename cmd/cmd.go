@@ -2,16 +2,30 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/triabokon/goscout/internal/crawler"
+	"github.com/triabokon/goscout/internal/frontier"
+	"github.com/triabokon/goscout/internal/normalize"
 	"github.com/triabokon/goscout/internal/parser"
+	"github.com/triabokon/goscout/internal/report"
+	"github.com/triabokon/goscout/internal/results"
+	"github.com/triabokon/goscout/internal/retry"
+	"github.com/triabokon/goscout/internal/robots"
+	"github.com/triabokon/goscout/internal/scope"
+	"github.com/triabokon/goscout/internal/seen"
+	"github.com/triabokon/goscout/internal/session"
 	"github.com/triabokon/goscout/internal/sitemap"
+	"github.com/triabokon/goscout/internal/state"
+	"github.com/triabokon/goscout/internal/throttle"
 )
 
 func Cmd() *cobra.Command {
@@ -25,72 +39,345 @@ func Cmd() *cobra.Command {
 	var config Config
 	cmd.Flags().AddFlagSet(config.Flags())
 
-	cmd.RunE = func(cmd *cobra.Command, args []string) (err error) {
-		if config.SiteURL == "" {
-			return fmt.Errorf("site url is required")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := config.Validate(); err != nil {
+			return err
 		}
-		if config.Crawler.WorkerCount < crawler.MinWorkerCount {
-			return fmt.Errorf("worker count should be greater than %d", crawler.MinWorkerCount)
+		ctx, stop := signalContext()
+		defer stop()
+		if config.StateDir == "" {
+			return crawl(ctx, config, nil, false)
 		}
-		if config.Crawler.QueueSize < crawler.MinQueueSize {
-			return fmt.Errorf("queue size should be greater than %d", crawler.MinQueueSize)
+		store, err := state.Open(config.StateDir)
+		if err != nil {
+			return fmt.Errorf("failed to open state: %w", err)
 		}
-		ctx := context.Background()
-		client := &http.Client{Timeout: config.HTTPTimeout}
-		c := crawler.New(config.Crawler, parser.New(client))
+		defer store.Close()
+		// the new crawl starts from scratch, so pages of the previous crawl in the directory are not resumed
+		if err = store.Reset(); err != nil {
+			return fmt.Errorf("failed to reset state: %w", err)
+		}
+		if err = store.SaveConfig(&config); err != nil {
+			return fmt.Errorf("failed to save config to state: %w", err)
+		}
+		return crawl(ctx, config, store, false)
+	}
+	cmd.AddCommand(ResumeCmd())
+	return cmd
+}
 
+// crawl crawls the website and writes its sitemap, the store is optional and could be nil,
+// the crawl is resumed from the state of the store if resume is set.
+func crawl(ctx context.Context, config Config, store *state.Store, resume bool) error {
+	if config.Sitemap.BaseURL == "" {
+		config.Sitemap.BaseURL = siteRoot(config.SiteURL)
+	}
+	s, err := sitemap.New(config.Sitemap)
+	if err != nil {
+		return fmt.Errorf("failed to create sitemap: %w", err)
+	}
+	normalizer, err := normalize.New(config.Normalize)
+	if err != nil {
+		return fmt.Errorf("failed to create url normalizer: %w", err)
+	}
+	siteURL, err := normalizer.Normalize(config.SiteURL)
+	if err != nil {
+		return fmt.Errorf("failed to normalize site url: %w", err)
+	}
+	crawlScope, err := scope.New(config.Scope, siteURL)
+	if err != nil {
+		return fmt.Errorf("failed to create crawl scope: %w", err)
+	}
+	parsedSiteURL, err := url.Parse(siteURL)
+	if err != nil || !crawlScope.InScope(parsedSiteURL) {
+		return fmt.Errorf("site url %s is out of the crawl scope", siteURL)
+	}
+	noRedirect := func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	// all requests share the session headers and cookies, so the site sees a single client
+	sess, err := session.New(config.Session)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	// the login form is submitted before the crawl, the session logs in again once requests are logged out
+	loginClient := &http.Client{Timeout: config.HTTPTimeout, CheckRedirect: noRedirect, Jar: sess.Jar()}
+	if err = sess.Login(ctx, loginClient); err != nil {
+		return fmt.Errorf("failed to log in: %w", err)
+	}
+	crawlFrontier, err := frontier.New(config.Frontier)
+	if err != nil {
+		return fmt.Errorf("failed to create frontier: %w", err)
+	}
+	defer crawlFrontier.Close()
+	seenURLs, err := seen.New(config.Seen)
+	if err != nil {
+		return fmt.Errorf("failed to create seen set: %w", err)
+	}
+	defer seenURLs.Close()
+	pageResults, err := results.New(config.Results)
+	if err != nil {
+		return fmt.Errorf("failed to create result set: %w", err)
+	}
+	defer pageResults.Close()
+	r, err := report.New(config.Report)
+	if err != nil {
+		return fmt.Errorf("failed to create report: %w", err)
+	}
+	// parser follows redirects itself to record them, while robots.txt redirects are followed by the client,
+	// robots.txt is fetched once per host, so it is not throttled
+	throttler := throttle.New(
+		config.Throttle, &http.Client{Timeout: config.HTTPTimeout, CheckRedirect: noRedirect, Jar: sess.Jar()},
+	)
+	// with upgrade scheme policy http urls of the scope are upgraded, so both variants are crawled once,
+	// hosts are probed while their pages are parsed and hold request slots of the throttler,
+	// so the probe, sent once per host, is not throttled to not wait for the slot of its own page
+	var urlUpgrader parser.Upgrader
+	var upgrader *scope.Upgrader
+	if config.Scope.Scheme == scope.SchemeUpgrade {
+		upgrader = scope.NewUpgrader(
+			sess.Client(&http.Client{Timeout: config.HTTPTimeout, CheckRedirect: noRedirect, Jar: sess.Jar()}),
+		)
+		urlUpgrader = upgrader
+		siteURL = upgrader.Upgrade(ctx, parsedSiteURL).String()
+	}
+	robotsClient := sess.Client(retry.New(config.Retry, &http.Client{Timeout: config.HTTPTimeout, Jar: sess.Jar()}))
+	// the nil store is passed as nil interface, so the crawler does not save the state
+	var crawlStore crawler.Store
+	if store != nil {
+		crawlStore = store
+	}
+	// stylesheets are fetched by the parser, so they follow the same robots rules and crawl delay as pages
+	config.Robots.UserAgent = config.Session.UserAgent
+	robotsChecker := robots.New(config.Robots, robotsClient)
+	c := crawler.New(
+		config.Crawler,
+		parser.New(
+			config.Parser, sess.Client(retry.New(config.Retry, throttler)), normalizer, crawlScope, urlUpgrader,
+			robotsChecker,
+		),
+		robotsChecker,
+		crawlFrontier,
+		seenURLs,
+		pageResults,
+		crawlStore,
+	)
+	if resume {
+		// saved pages are loaded into the result set, so they are not kept in memory
+		restored, lErr := store.Load(pageResults)
+		if lErr != nil {
+			return fmt.Errorf("failed to load crawl state: %w", lErr)
+		}
+		if err = c.Restore(restored); err != nil {
+			return fmt.Errorf("failed to restore crawl state: %w", err)
+		}
+		visited, lErr := pageResults.Len()
+		if lErr != nil {
+			return fmt.Errorf("failed to count restored pages: %w", lErr)
+		}
 		fmt.Printf(
-			"Start crawler with %d workers, queue size %d and crawling depth %d\n",
-			config.Crawler.WorkerCount, config.Crawler.QueueSize, config.Crawler.Depth,
+			"Restored crawl state with %d visited pages and %d pages left to crawl\n", visited, len(restored.Frontier),
 		)
-		c.Start(ctx)
-
-		fmt.Printf("Crawling website %s\n", config.SiteURL)
-		started := time.Now()
-		if err = c.Crawl(ctx, config.SiteURL, 1); err != nil {
-			return fmt.Errorf("failed to crawl web page: %w", err)
-		}
-		crawling := true
-		var elapsedTime time.Duration
-		for crawling {
-			<-time.Tick(config.CheckInterval)
-			fmt.Print(".")
-			if !c.HasWorkToDo() {
-				c.Stop()
-				crawling = false
-				elapsedTime = time.Since(started)
-				fmt.Print("\n")
+	}
+
+	fmt.Printf(
+		"Start crawler with %d workers, %s frontier order and crawling depth %d\n",
+		config.Crawler.WorkerCount, config.Frontier.Order, config.Crawler.Depth,
+	)
+	fmt.Printf("Crawling website %s\n", siteURL)
+	started := time.Now()
+	interrupted := false
+	var budgetErr *crawler.BudgetError
+	switch err := c.Run(ctx, siteURL); {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		// collected urls are still written, so the interrupted crawl is not wasted
+		interrupted = true
+	case errors.As(err, &budgetErr):
+	default:
+		return fmt.Errorf("failed to crawl website: %w", err)
+	}
+	elapsedTime := time.Since(started)
+	if budgetErr != nil {
+		fmt.Printf("Crawler was stopped, %s\n", budgetErr)
+	}
+
+	// log errors, because we need to write urls that we managed to find
+	if len(c.Errors()) != 0 {
+		fmt.Println("Following errors occurred during website crawling: ")
+		for _, e := range c.Errors() {
+			fmt.Println(e)
+		}
+		fmt.Println()
+	}
+
+	if slowdown := throttler.Slowdown(); len(slowdown) != 0 {
+		fmt.Println("Following hosts were slowed down due to slow or failing responses: ")
+		for origin, factor := range slowdown {
+			fmt.Printf("%s: request interval x%.1f\n", origin, factor)
+		}
+		fmt.Println()
+	}
+
+	skipped := c.SkippedURLs()
+	traps := crawler.TrapURLsCount(skipped)
+	trapURLs := 0
+	for _, count := range traps {
+		trapURLs += count
+	}
+	if len(skipped) > trapURLs {
+		fmt.Println("Following urls were skipped during website crawling: ")
+		for u, reason := range skipped {
+			// trap urls could be endless, so only their number is printed
+			if !reason.IsTrap() {
+				fmt.Printf("%s: %s\n", u, reason)
 			}
 		}
-		c.Wait()
+		fmt.Println()
+	}
+	if len(traps) != 0 {
+		fmt.Println("Following crawler traps were suppressed: ")
+		for reason, count := range traps {
+			fmt.Printf("%s: %d urls\n", reason, count)
+		}
+		fmt.Println()
+	}
+
+	// collected urls are counted with the set of the seen storage, so they are not kept in memory on large sites
+	collectedURLs, err := seen.New(config.Seen)
+	if err != nil {
+		return fmt.Errorf("failed to create collected url set: %w", err)
+	}
+	defer collectedURLs.Close()
+	summary, err := crawler.Summarize(c.Results(), collectedURLs)
+	if err != nil {
+		return fmt.Errorf("failed to summarize crawl: %w", err)
+	}
+	fmt.Printf(
+		"Crawler visited %d pages, collected %d unique urls in %s time\n",
+		summary.Pages, summary.UniqueURLs, elapsedTime,
+	)
+	if collapsed := normalizer.Collapsed(); collapsed != 0 {
+		fmt.Printf("%d duplicate url variants were collapsed by normalization\n", collapsed)
+	}
+	if summary.Retried != 0 {
+		fmt.Printf("%d pages were fetched after retries\n", summary.Retried)
+	}
+	if summary.Truncated != 0 {
+		fmt.Printf("%d pages were larger than the max body size and were parsed partially\n", summary.Truncated)
+	}
+	if upgrader != nil && len(upgrader.UpgradedHosts()) != 0 {
+		fmt.Printf("http urls were upgraded to https for hosts: %s\n", strings.Join(upgrader.UpgradedHosts(), ", "))
+	}
 
-		// log errors, because we need to write urls that we managed to find
-		if len(c.Errors()) != 0 {
-			fmt.Println("Following errors occurred during website crawling: ")
-			for _, e := range c.Errors() {
-				fmt.Println(e)
+	issues, err := report.RedirectIssues(c.Results(), crawlScope, config.Report.MaxRedirectHops)
+	if err != nil {
+		return fmt.Errorf("failed to find redirect issues: %w", err)
+	}
+	if len(issues) != 0 {
+		fmt.Println("Following redirect issues were found: ")
+		for _, i := range issues {
+			fmt.Printf("%s: %s: %s\n", i.URL, i.Issue, i.Chain)
+		}
+		fmt.Println()
+		if config.Report.RedirectsFileName != "" {
+			fmt.Printf("Writing redirect issues to %s ...\n", config.Report.RedirectsFileName)
+			if wErr := r.WriteRedirectsToFile(issues); wErr != nil {
+				return fmt.Errorf("failed to write redirect issues: %w", wErr)
 			}
-			fmt.Println()
 		}
+	}
 
-		seenURLs := c.SeenURLs()
-		fmt.Printf(
-			"Crawler visited %d pages, collected %d unique urls in %s time\n",
-			len(seenURLs), crawler.TotalUniqueURLsCount(seenURLs), elapsedTime,
-		)
+	if config.Report.FileName != "" {
+		fmt.Printf("Writing report to %s ...\n", config.Report.FileName)
+		if wErr := r.WriteToFile(c.Results()); wErr != nil {
+			return fmt.Errorf("failed to write report: %w", wErr)
+		}
+	}
 
-		fmt.Println("Generating sitemap ...")
-		s := sitemap.New(config.Sitemap)
-		s.GenerateSitemap(seenURLs, config.SiteURL)
+	fmt.Println("Generating sitemap ...")
+	pages := &sitemapPages{
+		results: c.Results(), seen: config.Seen, withURLs: config.Sitemap.Format == sitemap.FormatTree,
+	}
+	if err = s.GenerateSitemap(pages, siteURL); err != nil {
+		return fmt.Errorf("failed to generate sitemap: %w", err)
+	}
+	// the sitemap of the crawl stopped by a budget is partial as well, though it is expected to be
+	if interrupted || budgetErr != nil {
+		s.MarkPartial()
+	}
 
-		fmt.Printf("Writing sitemap to %s ...\n", config.FileName)
-		if wErr := s.WriteToFile(config.FileName); wErr != nil {
-			return fmt.Errorf("failed to write sitemap: %w", wErr)
+	fmt.Printf("Writing sitemap to %s ...\n", config.FileName)
+	files, wErr := s.WriteToFile(config.FileName)
+	if wErr != nil {
+		return fmt.Errorf("failed to write sitemap: %w", wErr)
+	}
+	if len(files) > 1 {
+		fmt.Printf("Sitemap is split into %d files: %s\n", len(files), strings.Join(files, ", "))
+	}
+	if interrupted {
+		return errors.New("crawl was interrupted, partial sitemap is written")
+	}
+	fmt.Println("Sitemap successfully written!")
+	return nil
+}
+
+// sitemapPages are page results listed in the sitemap, pages that could not be fetched are omitted.
+// Redirected pages are listed by their final url. Urls found on pages are kept only if withURLs is set,
+// since only the tree format needs them.
+type sitemapPages struct {
+	results crawler.ResultSet
+	// seen configures the set of listed urls, so pages are streamed from the results on large sites
+	seen     seen.Config
+	withURLs bool
+}
+
+// Range lists every page once, results are ranged ordered by depth,
+// so a page reached by several redirects is listed with the lowest depth.
+func (p *sitemapPages) Range(f func(loc string, page sitemap.Page) error) error {
+	// the bloom filter would wrongly leave some pages out of the sitemap, so they are kept on disk instead
+	listedConfig := p.seen
+	if listedConfig.Storage == seen.StorageBloom {
+		listedConfig.Storage = seen.StorageDisk
+	}
+	listed, err := seen.New(listedConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create listed url set: %w", err)
+	}
+	defer listed.Close()
+
+	return p.results.Range(func(r crawler.PageResult) error {
+		if r.Status == 0 && r.Error != "" {
+			return nil
+		}
+		loc := r.URL
+		if r.FinalURL != "" {
+			loc = r.FinalURL
+		}
+		added, aErr := listed.Add(loc)
+		if aErr != nil {
+			return fmt.Errorf("failed to add listed url: %w", aErr)
+		}
+		if !added {
+			return nil
+		}
+		page := sitemap.Page{
+			Depth: r.Depth, LastModified: r.LastModified, Status: r.Status, Canonical: r.Canonical, NoIndex: r.NoIndex,
 		}
-		fmt.Println("Sitemap successfully written!")
-		return nil
+		if p.withURLs {
+			page.URLs = r.URLs
+		}
+		return f(loc, page)
+	})
+}
+
+// siteRoot returns scheme and host of the site url, sitemap parts are published there by default.
+func siteRoot(siteURL string) string {
+	u, err := url.Parse(siteURL)
+	if err != nil || u.Host == "" {
+		return ""
 	}
-	return cmd
+	return u.Scheme + "://" + u.Host
 }
 
 func Execute() {
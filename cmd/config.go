@@ -1,22 +1,47 @@
 package cmd
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
+	"golang.org/x/net/html/charset"
 
 	"github.com/triabokon/goscout/internal/crawler"
+	"github.com/triabokon/goscout/internal/frontier"
+	"github.com/triabokon/goscout/internal/normalize"
+	"github.com/triabokon/goscout/internal/parser"
+	"github.com/triabokon/goscout/internal/report"
+	"github.com/triabokon/goscout/internal/results"
+	"github.com/triabokon/goscout/internal/retry"
+	"github.com/triabokon/goscout/internal/robots"
+	"github.com/triabokon/goscout/internal/scope"
+	"github.com/triabokon/goscout/internal/seen"
+	"github.com/triabokon/goscout/internal/session"
 	"github.com/triabokon/goscout/internal/sitemap"
+	"github.com/triabokon/goscout/internal/throttle"
 )
 
 type Config struct {
-	SiteURL       string
-	FileName      string
-	CheckInterval time.Duration
-	HTTPTimeout   time.Duration
+	SiteURL     string
+	FileName    string
+	HTTPTimeout time.Duration
+	StateDir    string
 
-	Crawler crawler.Config
-	Sitemap sitemap.Config
+	Crawler   crawler.Config
+	Frontier  frontier.Config
+	Seen      seen.Config
+	Results   results.Config
+	Parser    parser.Config
+	Normalize normalize.Config
+	Scope     scope.Config
+	Session   session.Config
+	Retry     retry.Config
+	Throttle  throttle.Config
+	Robots    robots.Config
+	Sitemap   sitemap.Config
+	Report    report.Config
 }
 
 func (c *Config) Flags() *pflag.FlagSet {
@@ -24,16 +49,54 @@ func (c *Config) Flags() *pflag.FlagSet {
 
 	f.StringVar(&c.SiteURL, "site_url", "", "url of the site to crawl")
 	f.StringVar(&c.FileName, "file_name", "sitemap.xml", "filename to write sitemap")
-	f.DurationVar(
-		&c.CheckInterval, "check_interval",
-		time.Second, "time interval to check if there are any pages left to crawl",
-	)
 	f.DurationVar(
 		&c.HTTPTimeout, "http_timeout",
 		10*time.Second, "timeout for http requests",
 	)
 
+	f.StringVar(
+		&c.StateDir, "state_dir",
+		"", "directory to save crawl state, so the crawl could be resumed with resume command",
+	)
+
 	f.AddFlagSet(c.Crawler.Flags("crawler"))
+	f.AddFlagSet(c.Frontier.Flags("frontier"))
+	f.AddFlagSet(c.Seen.Flags("seen"))
+	f.AddFlagSet(c.Results.Flags("results"))
+	f.AddFlagSet(c.Parser.Flags("parser"))
+	f.AddFlagSet(c.Normalize.Flags("normalize"))
+	f.AddFlagSet(c.Scope.Flags("scope"))
+	f.AddFlagSet(c.Session.Flags("session"))
+	f.AddFlagSet(c.Retry.Flags("retry"))
+	f.AddFlagSet(c.Throttle.Flags("throttle"))
+	f.AddFlagSet(c.Robots.Flags("robots"))
 	f.AddFlagSet(c.Sitemap.Flags("sitemap"))
+	f.AddFlagSet(c.Report.Flags("report"))
 	return f
 }
+
+func (c *Config) Validate() error {
+	if c.SiteURL == "" {
+		return fmt.Errorf("site url is required")
+	}
+	if c.Crawler.WorkerCount < crawler.MinWorkerCount {
+		return fmt.Errorf("worker count should be greater than %d", crawler.MinWorkerCount)
+	}
+	if c.Retry.MaxAttempts < 1 {
+		return fmt.Errorf("retry max attempts should be at least 1")
+	}
+	if c.Throttle.MaxSlowdown < 1 {
+		return fmt.Errorf("throttle max slowdown should be at least 1")
+	}
+	if e, _ := charset.Lookup(c.Parser.DefaultCharset); e == nil && c.Parser.DefaultCharset != "" {
+		return fmt.Errorf("unknown parser default charset %q", c.Parser.DefaultCharset)
+	}
+	// the config is saved to the crawl state, so secrets are only saved as references
+	if plain := c.Session.PlainSecrets(); c.StateDir != "" && len(plain) != 0 {
+		return fmt.Errorf(
+			"secrets of %s should be read with env: or file: prefix, since the config is saved to the crawl state",
+			strings.Join(plain, ", "),
+		)
+	}
+	return nil
+}
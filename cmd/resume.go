@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triabokon/goscout/internal/state"
+)
+
+func ResumeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "resume",
+		Short:        "Resume interrupted crawl from its state directory.",
+		SilenceUsage: true,
+	}
+
+	var stateDir string
+	cmd.Flags().StringVar(&stateDir, "state", "", "directory with saved crawl state")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if stateDir == "" {
+			return fmt.Errorf("state directory is required")
+		}
+		store, err := state.Open(stateDir)
+		if err != nil {
+			return fmt.Errorf("failed to open state: %w", err)
+		}
+		defer store.Close()
+
+		// defaults are set first, so options added after the state was saved keep their default values
+		var config Config
+		config.Flags()
+		if err = store.LoadConfig(&config); err != nil {
+			return fmt.Errorf("failed to load config from state: %w", err)
+		}
+		config.StateDir = stateDir
+		if err = config.Validate(); err != nil {
+			return err
+		}
+		ctx, stop := signalContext()
+		defer stop()
+		return crawl(ctx, config, store, true)
+	}
+	return cmd
+}
@@ -0,0 +1,74 @@
+package boltdb
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// openTimeout is the time to wait for the lock of the database, which is held while another process uses it.
+const openTimeout = time.Second
+
+// Open opens the database at the path, creating it along with its buckets if needed.
+func Open(path string, buckets ...string) (*bbolt.DB, error) {
+	return open(path, &bbolt.Options{Timeout: openTimeout}, buckets)
+}
+
+// Temp is a temporary database, it is removed on Close.
+type Temp struct {
+	*bbolt.DB
+	path string
+}
+
+// OpenTemp creates the temporary database with the buckets in the directory, the system temporary directory
+// is used if it is empty. The database is not synced to disk, since it is not kept after the crawl.
+func OpenTemp(dir, pattern string, buckets ...string) (*Temp, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database file: %w", err)
+	}
+	path := file.Name()
+	if err = file.Close(); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to close database file: %w", err)
+	}
+	db, err := open(path, &bbolt.Options{Timeout: openTimeout, NoSync: true}, buckets)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return &Temp{DB: db, path: path}, nil
+}
+
+// Close closes and removes the database.
+func (t *Temp) Close() error {
+	if err := t.DB.Close(); err != nil {
+		return fmt.Errorf("failed to close database: %w", err)
+	}
+	if err := os.Remove(t.path); err != nil {
+		return fmt.Errorf("failed to remove database: %w", err)
+	}
+	return nil
+}
+
+func open(path string, options *bbolt.Options, buckets []string) (*bbolt.DB, error) {
+	db, err := bbolt.Open(path, 0o600, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, b := range buckets {
+			if _, bErr := tx.CreateBucketIfNotExists([]byte(b)); bErr != nil {
+				return fmt.Errorf("failed to create bucket %s: %w", b, bErr)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to init database: %w", err)
+	}
+	return db, nil
+}
@@ -1,20 +1,32 @@
 package crawler
 
 import (
+	"time"
+
 	"github.com/spf13/pflag"
 
 	"github.com/triabokon/goscout/flags"
 )
 
-const (
-	MinWorkerCount = 10
-	MinQueueSize   = 100
-)
+const MinWorkerCount = 10
 
 type Config struct {
 	WorkerCount int
-	QueueSize   int
 	Depth       int
+
+	CheckpointInterval time.Duration
+	ShutdownTimeout    time.Duration
+
+	// budgets stop the crawl once reached, zero disables the budget
+	MaxPages    int
+	MaxDuration time.Duration
+	MaxBytes    int64
+
+	// trap limits skip urls of crawler traps, zero disables the limit
+	TrapMaxPathDepth        int
+	TrapMaxRepeatedSegments int
+	TrapMaxQueryVariants    int
+	TrapMaxURLLength        int
 }
 
 func (c *Config) Flags(prefix string) *pflag.FlagSet {
@@ -22,11 +34,41 @@ func (c *Config) Flags(prefix string) *pflag.FlagSet {
 	f := pflag.NewFlagSet(name, pflag.PanicOnError)
 
 	f.IntVar(&c.WorkerCount, "worker_count", 100, "number of workers for crawler (min 10)")
+	f.IntVar(&c.Depth, "depth", 100, "maximum depth the crawler would go")
+	f.DurationVar(
+		&c.CheckpointInterval, "checkpoint_interval",
+		30*time.Second, "time interval to save crawl state when state directory is set",
+	)
+	f.DurationVar(
+		&c.ShutdownTimeout, "shutdown_timeout",
+		10*time.Second, "time to let pages that are being crawled finish after interruption",
+	)
+	f.IntVar(&c.MaxPages, "max_pages", 0, "maximum number of pages to fetch before the crawl is stopped, 0 disables the limit")
+	f.DurationVar(
+		&c.MaxDuration, "max_duration",
+		0, "maximum duration of the crawl before it is stopped, 0 disables the limit",
+	)
+	f.Int64Var(
+		&c.MaxBytes, "max_bytes",
+		0, "maximum number of body bytes to download before the crawl is stopped, 0 disables the limit",
+	)
 	f.IntVar(
-		&c.QueueSize, "queue_size",
-		1000, "maximum number of tasks that queue can store (min 100)",
+		&c.TrapMaxPathDepth, "trap_max_path_depth",
+		20, "urls with more path segments are skipped as crawler traps, 0 disables the limit",
+	)
+	f.IntVar(
+		&c.TrapMaxRepeatedSegments, "trap_max_repeated_segments",
+		3, "urls with a path segment repeated more times are skipped as crawler traps, 0 disables the limit",
+	)
+	f.IntVar(
+		&c.TrapMaxQueryVariants, "trap_max_query_variants",
+		100, "maximum number of urls with the same path and different queries, "+
+			"other variants are skipped as crawler traps, 0 disables the limit",
+	)
+	f.IntVar(
+		&c.TrapMaxURLLength, "trap_max_url_length",
+		2048, "longer urls are skipped as crawler traps, 0 disables the limit",
 	)
-	f.IntVar(&c.Depth, "depth", 100, "maximum depth the crawler would go")
 
 	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
 }
@@ -2,148 +2,555 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"github.com/triabokon/goscout/internal/parser"
+	"github.com/triabokon/goscout/internal/retry"
 )
 
 //go:generate mockgen -destination=./mocks/parser_mock.go -package=mocks github.com/triabokon/goscout/internal/crawler Parser
 type Parser interface {
-	ExtractURLs(u string) (webURLs, staticURLs []string, err error)
+	ExtractURLs(ctx context.Context, u string) (*parser.Page, error)
+}
+
+//go:generate mockgen -destination=./mocks/robots_mock.go -package=mocks github.com/triabokon/goscout/internal/crawler Robots
+type Robots interface {
+	Allowed(ctx context.Context, u string) (bool, error)
+	Wait(ctx context.Context, u string) error
+}
+
+// Frontier keeps jobs waiting to be crawled and decides their order, the crawler guards it with a mutex.
+//
+//go:generate mockgen -destination=./mocks/frontier_mock.go -package=mocks github.com/triabokon/goscout/internal/crawler Frontier
+type Frontier interface {
+	// Push queues the job, returning the dropped job if the frontier is full
+	Push(j Job) (Job, bool, error)
+	// Pop returns the next job to crawl, it returns false if the frontier is empty
+	Pop() (Job, bool, error)
+	// Jobs returns all queued jobs
+	Jobs() ([]Job, error)
+}
+
+// SeenSet keeps urls that were crawled or are being crawled, so each url is crawled once, it is used concurrently.
+//
+//go:generate mockgen -destination=./mocks/seen_set_mock.go -package=mocks github.com/triabokon/goscout/internal/crawler SeenSet
+type SeenSet interface {
+	// Add adds the url, it returns false if the url was already seen
+	Add(u string) (bool, error)
+	Contains(u string) (bool, error)
+}
+
+// ResultSet keeps results of crawled pages, so they are not kept in memory of the crawler, it is used concurrently.
+//
+//go:generate mockgen -destination=./mocks/result_set_mock.go -package=mocks github.com/triabokon/goscout/internal/crawler ResultSet
+type ResultSet interface {
+	// Put stores the page result, replacing the previous result of its url
+	Put(r PageResult) error
+	// Range calls f for results ordered by depth and url, it stops on the first error of f
+	Range(f func(r PageResult) error) error
+	// Len returns the number of results
+	Len() (int, error)
 }
 
 type Crawler struct {
-	config Config
-	parser Parser
+	config   Config
+	parser   Parser
+	robots   Robots
+	frontier Frontier
+	seen     SeenSet
+	results  ResultSet
+	store    Store
 
-	wg            *sync.WaitGroup
-	seenURLs      *sync.Map
-	activeWorkers int64
-	queue         chan Job
-	errc          chan error
-	errors        []error
+	skippedURLs *sync.Map
+	// unsavedResults and unsavedSkipped are results and skip reasons changed since the previous state snapshot,
+	// they are tracked only with the store, so checkpoints save only changes of the crawl
+	unsavedResults *sync.Map
+	unsavedSkipped *sync.Map
+	traps          *trapDetector
+	// inFlight keeps jobs that are being crawled, so they are saved in the state along with queued ones
+	inFlight *sync.Map
+	// restored jobs are scheduled on the next Run
+	restored []Job
+	// stateMu is held for reading while jobs change frontier or page results,
+	// and for writing while taking a consistent snapshot of the state
+	stateMu *sync.RWMutex
+	// stop is closed when the crawler is asked to stop or a budget is reached, so no new jobs are crawled
+	stop      <-chan struct{}
+	stopCrawl context.CancelFunc
+	// budgetErr is the first reached budget that stopped the crawl, it is guarded by errMu
+	budgetErr *BudgetError
+	// fetched is the number of pages fetched, downloaded is the number of body bytes read including stylesheets
+	fetched    int64
+	downloaded int64
+	// mu guards the frontier and the number of active jobs, workers wait on cond for new jobs
+	mu     *sync.Mutex
+	cond   *sync.Cond
+	active int
+	errc   chan error
+	errMu  *sync.Mutex
+	errors []error
 }
 
 type Job struct {
 	URL   string
 	Depth int
+	// Parent is the url of the page where the url was found, empty for seed urls
+	Parent string
 }
 
-func New(c Config, p Parser) *Crawler {
+// PageResult is the result of crawling a web page.
+type PageResult struct {
+	URL string
+	// FinalURL is the url of the page after redirects
+	FinalURL string
+	// Redirects is the chain of redirects from the url to the final url
+	Redirects   []parser.Redirect
+	Status      int
+	ContentType string
+	// Size is the response body size in bytes
+	Size int64
+	// Latency is the time until the response headers were received
+	Latency time.Duration
+	// Truncated is set if the page body is larger than the max body size
+	Truncated bool
+	Depth     int
+	Parent    string
+	FetchedAt time.Time
+	// Attempts is the number of requests made to fetch the page, including retries
+	Attempts int
+	// Error is set if the page could not be fetched or has non-2xx status, such page has no urls
+	Error string
+
+	// URLs are web and static urls found on the page
+	URLs         []string
+	LastModified time.Time
+	// Canonical is the canonical url the page points to, empty if not set
+	Canonical string
+	// NoIndex is set if the page asks not to be indexed
+	NoIndex bool
+	// NoFollow is set if the page asks not to follow its links, so they were not crawled
+	NoFollow bool
+}
+
+// New creates a crawler, the store is optional and could be nil if crawl state should not be saved.
+func New(c Config, p Parser, r Robots, f Frontier, seen SeenSet, results ResultSet, s Store) *Crawler {
+	mu := &sync.Mutex{}
 	return &Crawler{
-		config:   c,
-		parser:   p,
-		wg:       &sync.WaitGroup{},
-		seenURLs: &sync.Map{},
-		queue:    make(chan Job, c.QueueSize),
-		errc:     make(chan error),
+		config:         c,
+		parser:         p,
+		robots:         r,
+		frontier:       f,
+		seen:           seen,
+		results:        results,
+		store:          s,
+		skippedURLs:    &sync.Map{},
+		unsavedResults: &sync.Map{},
+		unsavedSkipped: &sync.Map{},
+		traps:          newTrapDetector(c),
+		inFlight:       &sync.Map{},
+		stateMu:        &sync.RWMutex{},
+		mu:             mu,
+		cond:           sync.NewCond(mu),
+		errc:           make(chan error),
+		errMu:          &sync.Mutex{},
 	}
 }
 
-// Crawl crawls web page, extracting and filtering its urls, then add found urls to the queue.
+// Crawl crawls web page, extracting and filtering its urls, then add found urls to the frontier.
 func (c *Crawler) Crawl(ctx context.Context, url string, depth int) error {
-	// check if url has already been visited
-	if _, ok := c.seenURLs.Load(url); ok {
+	return c.crawl(ctx, Job{URL: url, Depth: depth})
+}
+
+func (c *Crawler) crawl(ctx context.Context, j Job) error {
+	// add url to the seen set, so other workers would not process it again,
+	// if the url has already been visited there is nothing to do
+	added, err := c.seen.Add(j.URL)
+	if err != nil {
+		return fmt.Errorf("failed to mark url as seen: %w", err)
+	}
+	if !added {
 		return nil
 	}
-	// store url to the map of visited urls, so other workers would not process it again
-	c.seenURLs.Store(url, nil)
-	if depth > c.config.Depth {
+	// the url could be skipped as a nofollow link of another page before it was found by a followed link
+	c.unskip(j.URL)
+	if j.Depth > c.config.Depth {
 		return ErrExceedsDepth
 	}
-	// extract all urls from the given web page
-	webURLs, staticURLs, err := c.parser.ExtractURLs(url)
+	// respect crawl-delay of the host before fetching the page
+	if err := c.robots.Wait(ctx, j.URL); err != nil {
+		return fmt.Errorf("failed to wait for crawl delay: %w", err)
+	}
+	// the page over the budget is not fetched, it is left in the frontier as if the crawl was interrupted
+	if fetched := atomic.AddInt64(&c.fetched, 1); c.config.MaxPages > 0 && fetched > int64(c.config.MaxPages) {
+		c.exceedBudget(BudgetPages, strconv.Itoa(c.config.MaxPages))
+		return ErrStopped
+	}
+	// extract all urls from the given web page, counting attempts made by the http client
+	fetchCtx := retry.WithAttempts(ctx)
+	result := PageResult{URL: j.URL, Depth: j.Depth, Parent: j.Parent, FetchedAt: time.Now()}
+	page, err := c.parser.ExtractURLs(fetchCtx, j.URL)
+	result.Attempts = retry.Attempts(fetchCtx)
 	if err != nil {
+		// the failed page is recorded, so it is reported, unless it was interrupted by the stop
+		// and is going to be crawled again
+		if !c.stopped() {
+			result.Error = err.Error()
+			var statusErr *parser.StatusError
+			if errors.As(err, &statusErr) {
+				result.Status = statusErr.StatusCode
+				result.FinalURL = statusErr.FinalURL
+				result.Redirects = statusErr.Redirects
+			}
+			var redirectErr *parser.RedirectError
+			if errors.As(err, &redirectErr) {
+				result.Redirects = redirectErr.Redirects
+			}
+			if sErr := c.storeResult(result); sErr != nil {
+				return fmt.Errorf("failed to store page result: %w", sErr)
+			}
+		}
 		return fmt.Errorf("failed to extract url from web page: %w", err)
 	}
 
-	filteredWebURLs, err := filterWebURLs(webURLs, c.seenURLs)
+	downloaded := atomic.AddInt64(&c.downloaded, page.Size+page.StylesheetsSize)
+	if c.config.MaxBytes > 0 && downloaded >= c.config.MaxBytes {
+		c.exceedBudget(BudgetBytes, strconv.FormatInt(c.config.MaxBytes, 10))
+	}
+
+	filteredWebURLs, err := filterWebURLs(page.WebURLs, c.seen)
 	if err != nil {
 		return fmt.Errorf("failed to filter web urls: %w", err)
 	}
-	filteredStaticURLs, err := filterStaticURLs(staticURLs)
+	filteredWebURLs = c.filterTrapURLs(filteredWebURLs)
+	filteredWebURLs, err = c.filterRobotsURLs(ctx, filteredWebURLs)
+	if err != nil {
+		return fmt.Errorf("failed to check robots rules: %w", err)
+	}
+	filteredStaticURLs, err := filterStaticURLs(page.StaticURLs)
 	if err != nil {
 		return fmt.Errorf("failed to filter static urls: %w", err)
 	}
-	// update value in the seenURLs with newly found urls
-	c.seenURLs.Store(url, append(filteredWebURLs, filteredStaticURLs...))
+	noFollowURLs, err := filterWebURLs(page.NoFollowURLs, c.seen)
+	if err != nil {
+		return fmt.Errorf("failed to filter nofollow urls: %w", err)
+	}
+	// links are not followed if the page asks so, they are still recorded as found on the page
+	if page.NoFollow {
+		noFollowURLs = append(noFollowURLs, filteredWebURLs...)
+		filteredWebURLs = nil
+	}
+	for _, u := range noFollowURLs {
+		c.skip(u, SkipReasonNoFollow)
+	}
+	// the page is redirected to another url of the site, so it is not crawled again by that url
+	if page.FinalURL != "" && page.FinalURL != j.URL {
+		if _, sErr := c.seen.Add(page.FinalURL); sErr != nil {
+			return fmt.Errorf("failed to mark final url as seen: %w", sErr)
+		}
+	}
+	// store the result of the crawled page with newly found urls
+	result.FinalURL = page.FinalURL
+	result.Redirects = page.Redirects
+	result.Status = page.StatusCode
+	result.ContentType = page.ContentType
+	result.Size = page.Size
+	result.Latency = page.Latency
+	result.Truncated = page.Truncated
+	result.URLs = unique(append(append(filteredWebURLs, noFollowURLs...), filteredStaticURLs...))
+	result.LastModified = page.LastModified
+	result.Canonical = page.Canonical
+	result.NoIndex = page.NoIndex
+	result.NoFollow = page.NoFollow
+	if err = c.storeResult(result); err != nil {
+		return fmt.Errorf("failed to store page result: %w", err)
+	}
 
 	for _, u := range filteredWebURLs {
-		select {
-		// check for context cancellation
-		case <-ctx.Done():
-			return ctx.Err()
-		// send this url to the queue
-		case c.queue <- Job{URL: u, Depth: depth + 1}:
-		default:
-			// if the queue is full, then crawl this url immediately
-			cErr := c.Crawl(ctx, u, depth+1)
-			switch cErr {
-			case nil:
-			case ErrExceedsDepth:
-				return nil
-			default:
-				return cErr
-			}
+		if sErr := c.schedule(Job{URL: u, Depth: j.Depth + 1, Parent: j.URL}); sErr != nil {
+			return fmt.Errorf("failed to schedule url: %w", sErr)
 		}
 	}
 	return nil
 }
 
-// Start initializes multiple workers based on the WorkerCount from the Config.
-func (c *Crawler) Start(ctx context.Context) {
-	for w := 0; w < c.config.WorkerCount; w++ {
-		c.wg.Add(1)
-		go func() {
-			defer c.wg.Done()
-			c.worker(ctx)
-		}()
+// Run crawls seed urls and all pages reachable from them using WorkerCount workers.
+// It returns once the frontier is empty and all workers have exited.
+// When ctx is cancelled no new pages are crawled, and pages that are being crawled
+// are given ShutdownTimeout to finish before their requests are aborted.
+func (c *Crawler) Run(ctx context.Context, seeds ...string) error {
+	seeds, err := c.filterRobotsURLs(ctx, seeds)
+	if err != nil {
+		return fmt.Errorf("failed to check robots rules: %w", err)
+	}
+	// jobs are scheduled before workers start, so workers do not exit on the empty frontier
+	jobs := c.restored
+	for _, s := range seeds {
+		jobs = append(jobs, Job{URL: s, Depth: 1})
+	}
+	for _, j := range jobs {
+		if err = c.schedule(j); err != nil {
+			return fmt.Errorf("failed to schedule url: %w", err)
+		}
 	}
-	c.wg.Add(1)
+	// the crawl is stopped the same way when ctx is cancelled and when a budget is reached
+	stopCtx, stopCrawl := context.WithCancel(ctx)
+	defer stopCrawl()
+	c.stop, c.stopCrawl = stopCtx.Done(), stopCrawl
+	if c.config.MaxDuration > 0 {
+		timer := time.AfterFunc(c.config.MaxDuration, func() {
+			c.exceedBudget(BudgetDuration, c.config.MaxDuration.String())
+		})
+		defer timer.Stop()
+	}
+	crawlCtx, cancelCrawl := context.WithCancel(context.Background())
+	defer cancelCrawl()
 	go func() {
-		defer c.wg.Done()
+		select {
+		case <-stopCtx.Done():
+		case <-crawlCtx.Done():
+			return
+		}
+		// wake up idle workers, so they exit leaving queued jobs in the frontier
+		c.mu.Lock()
+		c.cond.Broadcast()
+		c.mu.Unlock()
+		timer := time.NewTimer(c.config.ShutdownTimeout)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancelCrawl()
+		case <-crawlCtx.Done():
+		}
+	}()
+
+	errorsDone := make(chan struct{})
+	go func() {
+		defer close(errorsDone)
 		for e := range c.errc {
+			c.errMu.Lock()
 			c.errors = append(c.errors, e)
+			c.errMu.Unlock()
 		}
 	}()
+	stopCheckpoints := make(chan struct{})
+	checkpointsDone := make(chan struct{})
+	go func() {
+		defer close(checkpointsDone)
+		c.checkpoints(stopCheckpoints)
+	}()
+	workers := &sync.WaitGroup{}
+	for w := 0; w < c.config.WorkerCount; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			c.worker(crawlCtx)
+		}()
+	}
+	workers.Wait()
+	close(stopCheckpoints)
+	<-checkpointsDone
+	// save the final state, so the crawl could be resumed if it was interrupted
+	if c.store != nil {
+		c.checkpoint()
+	}
+	// nobody could send errors after all workers have exited
+	close(c.errc)
+	<-errorsDone
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.budgetErr != nil {
+		return c.budgetErr
+	}
+	return nil
+}
+
+// Results returns results of crawled pages.
+func (c *Crawler) Results() ResultSet {
+	return c.results
 }
 
-func (c *Crawler) SeenURLs() map[string][]string {
-	return seenURLsToMap(c.seenURLs)
+// SkippedURLs returns urls that were found but not crawled, mapped to the skip reason.
+func (c *Crawler) SkippedURLs() map[string]SkipReason {
+	return skippedURLsToMap(c.skippedURLs)
 }
 
 func (c *Crawler) Errors() []error {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
 	return c.errors
 }
 
-// HasWorkToDo determines if there are any active workers or pending urls in the queue.
-// We could close the queue and stop Crawler if not.
-func (c *Crawler) HasWorkToDo() bool {
-	return int(c.activeWorkers)+len(c.queue) > 0
+// worker crawls jobs from the frontier until no jobs are left or the crawler is stopped.
+func (c *Crawler) worker(ctx context.Context) {
+	for {
+		j, ok := c.next()
+		if !ok {
+			return
+		}
+		c.process(ctx, j)
+	}
+}
+
+// next waits for the next job from the frontier, it returns false once the frontier is empty
+// and no active job could schedule new ones, or the crawler is stopped.
+func (c *Crawler) next() (Job, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for {
+		// queued jobs are left in the frontier to be crawled when the state is restored
+		if c.stopped() {
+			return Job{}, false
+		}
+		j, ok, err := c.frontier.Pop()
+		if err != nil {
+			c.errc <- fmt.Errorf("failed to get next job: %w", err)
+			return Job{}, false
+		}
+		if ok {
+			c.active++
+			c.inFlight.Store(j.URL, j)
+			return j, true
+		}
+		if c.active == 0 {
+			return Job{}, false
+		}
+		c.cond.Wait()
+	}
 }
 
-func (c *Crawler) Wait() {
-	c.wg.Wait()
+// schedule adds the job to the frontier, it never blocks, so pages are crawled only by workers.
+// Jobs deeper than the crawling depth are not queued, the job dropped by the full frontier is skipped.
+func (c *Crawler) schedule(j Job) error {
+	if j.Depth > c.config.Depth {
+		return nil
+	}
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	dropped, ok, err := c.frontier.Push(j)
+	if err != nil {
+		return err
+	}
+	if ok {
+		c.skip(dropped.URL, SkipReasonFrontierFull)
+	}
+	c.cond.Signal()
+	return nil
 }
 
-func (c *Crawler) Stop() {
-	close(c.queue)
-	close(c.errc)
+// process crawls the job, reports its error and marks the job as done.
+func (c *Crawler) process(ctx context.Context, j Job) {
+	defer c.done()
+	err := c.crawl(ctx, j)
+	// the job interrupted by the stop is left in the state to be crawled when it is restored
+	if err != nil && c.stopped() {
+		return
+	}
+	c.stateMu.RLock()
+	c.inFlight.Delete(j.URL)
+	c.stateMu.RUnlock()
+	switch err {
+	case nil, ErrExceedsDepth:
+	default:
+		c.errc <- fmt.Errorf("failed to crawl web page: %w", err)
+	}
 }
 
-// worker is process urls from the queue by calling the Crawl method.
-func (c *Crawler) worker(ctx context.Context) {
-	for j := range c.queue {
-		// increment the count of active workers
-		atomic.AddInt64(&c.activeWorkers, 1)
-		err := c.Crawl(ctx, j.URL, j.Depth)
-		// once the url has been crawled, decrement the count of active workers
-		atomic.AddInt64(&c.activeWorkers, -1)
-		switch err {
-		case nil, ErrExceedsDepth:
+// done marks the active job as done, waking up idle workers to exit once no jobs are left.
+func (c *Crawler) done() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.active--
+	if c.active == 0 {
+		c.cond.Broadcast()
+	}
+}
+
+// storeResult stores the page result, it is saved by the next checkpoint.
+func (c *Crawler) storeResult(r PageResult) error {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	if err := c.results.Put(r); err != nil {
+		return err
+	}
+	if c.store != nil {
+		c.unsavedResults.Store(r.URL, r)
+	}
+	return nil
+}
+
+// skip records the url as skipped for the reason, it is saved by the next checkpoint.
+func (c *Crawler) skip(u string, reason SkipReason) {
+	c.skippedURLs.Store(u, reason)
+	if c.store != nil {
+		c.unsavedSkipped.Store(u, reason)
+	}
+}
+
+// unskip removes the url from skipped urls, since it is crawled after all.
+func (c *Crawler) unskip(u string) {
+	if _, ok := c.skippedURLs.LoadAndDelete(u); ok && c.store != nil {
+		c.unsavedSkipped.Store(u, SkipReason(""))
+	}
+}
+
+// exceedBudget stops the crawl because of the budget, only the first reached budget is recorded.
+func (c *Crawler) exceedBudget(b Budget, limit string) {
+	c.errMu.Lock()
+	defer c.errMu.Unlock()
+	if c.budgetErr != nil {
+		return
+	}
+	c.budgetErr = &BudgetError{Budget: b, Limit: limit}
+	if c.stopCrawl != nil {
+		c.stopCrawl()
+	}
+}
+
+func (c *Crawler) stopped() bool {
+	select {
+	case <-c.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// filterTrapURLs filters urls that look like crawler traps, recording them as skipped.
+func (c *Crawler) filterTrapURLs(urls []string) []string {
+	filtered := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if reason := c.traps.check(u); reason != "" {
+			c.skip(u, reason)
+			continue
+		}
+		filtered = append(filtered, u)
+	}
+	return filtered
+}
+
+// filterRobotsURLs filters urls disallowed by robots.txt, recording them as skipped.
+func (c *Crawler) filterRobotsURLs(ctx context.Context, urls []string) ([]string, error) {
+	filtered := make([]string, 0, len(urls))
+	for _, u := range urls {
+		allowed, err := c.robots.Allowed(ctx, u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check url %s: %w", u, err)
+		}
+		if !allowed {
+			c.skip(u, SkipReasonRobotsDisallowed)
 			continue
-		default:
-			c.errc <- fmt.Errorf("failed to crawl web page: %w", err)
 		}
+		filtered = append(filtered, u)
 	}
+	return filtered, nil
 }
@@ -3,41 +3,87 @@ package crawler_test
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/triabokon/goscout/internal/crawler"
 	"github.com/triabokon/goscout/internal/crawler/mocks"
+	"github.com/triabokon/goscout/internal/frontier"
+	"github.com/triabokon/goscout/internal/parser"
+	"github.com/triabokon/goscout/internal/results"
+	"github.com/triabokon/goscout/internal/seen"
 )
 
 var gfi = gofakeit.New(1)
 
+// pageURLs returns urls found on crawled pages.
+func pageURLs(pages map[string]crawler.PageResult) map[string][]string {
+	urls := make(map[string][]string, len(pages))
+	for u, r := range pages {
+		urls[u] = r.URLs
+	}
+	return urls
+}
+
+// crawledPages returns results of crawled pages by their urls.
+func crawledPages(t *testing.T, c *crawler.Crawler) map[string]crawler.PageResult {
+	pages := make(map[string]crawler.PageResult)
+	require.NoError(t, c.Results().Range(func(r crawler.PageResult) error {
+		pages[r.URL] = r
+		return nil
+	}))
+	return pages
+}
+
+// newFrontier creates the breadth-first frontier, zero max size disables its limit.
+func newFrontier(t *testing.T, maxSize int) crawler.Frontier {
+	f, err := frontier.NewMemory(frontier.Config{Order: frontier.OrderBFS, MaxSize: maxSize})
+	require.NoError(t, err)
+	return f
+}
+
 func TestCrawler_Crawl(t *testing.T) {
 	t.Run("errors", func(t *testing.T) {
 		startURL := gfi.URL()
 		for name, tc := range map[string]struct {
-			tuneMock func(p *mocks.MockParser)
+			tuneMock func(p *mocks.MockParser, r *mocks.MockRobots)
 			errorMsg string
 		}{
 			"url extraction error": {
 				errorMsg: "failed to extract url from web page",
-				tuneMock: func(p *mocks.MockParser) {
-					p.EXPECT().ExtractURLs(startURL).Return(nil, nil, fmt.Errorf("url extraction error"))
+				tuneMock: func(p *mocks.MockParser, r *mocks.MockRobots) {
+					p.EXPECT().ExtractURLs(gomock.Any(), startURL).Return(nil, fmt.Errorf("url extraction error"))
 				},
 			},
 			"web url filtering error": {
 				errorMsg: "failed to filter web urls",
-				tuneMock: func(p *mocks.MockParser) {
-					p.EXPECT().ExtractURLs(startURL).Return([]string{":"}, nil, nil)
+				tuneMock: func(p *mocks.MockParser, r *mocks.MockRobots) {
+					p.EXPECT().ExtractURLs(gomock.Any(), startURL).Return(&parser.Page{WebURLs: []string{":"}}, nil)
 				},
 			},
 			"static url filtering error": {
 				errorMsg: "failed to filter static urls",
-				tuneMock: func(p *mocks.MockParser) {
-					p.EXPECT().ExtractURLs(startURL).Return(nil, []string{":"}, nil)
+				tuneMock: func(p *mocks.MockParser, r *mocks.MockRobots) {
+					p.EXPECT().ExtractURLs(gomock.Any(), startURL).Return(&parser.Page{StaticURLs: []string{":"}}, nil)
+				},
+			},
+			"crawl delay error": {
+				errorMsg: "failed to wait for crawl delay",
+				tuneMock: func(p *mocks.MockParser, r *mocks.MockRobots) {
+					r.EXPECT().Wait(gomock.Any(), startURL).Return(context.Canceled)
+				},
+			},
+			"robots check error": {
+				errorMsg: "failed to check robots rules",
+				tuneMock: func(p *mocks.MockParser, r *mocks.MockRobots) {
+					p.EXPECT().ExtractURLs(gomock.Any(), startURL).Return(&parser.Page{WebURLs: []string{"https://example.com/page"}}, nil)
+					r.EXPECT().Allowed(gomock.Any(), "https://example.com/page").Return(false, fmt.Errorf("robots error"))
 				},
 			},
 		} {
@@ -46,11 +92,13 @@ func TestCrawler_Crawl(t *testing.T) {
 				defer ctrl.Finish()
 
 				ctx := context.Background()
-				parser := mocks.NewMockParser(ctrl)
+				mockParser := mocks.NewMockParser(ctrl)
+				robots := mocks.NewMockRobots(ctrl)
 
-				tc.tuneMock(parser)
+				tc.tuneMock(mockParser, robots)
+				robots.EXPECT().Wait(gomock.Any(), startURL).Return(nil).AnyTimes()
 
-				c := crawler.New(crawler.Config{Depth: 3}, parser)
+				c := crawler.New(crawler.Config{Depth: 3}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
 				err := c.Crawl(ctx, startURL, 1)
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tc.errorMsg)
@@ -63,9 +111,9 @@ func TestCrawler_Crawl(t *testing.T) {
 		defer ctrl.Finish()
 
 		ctx := context.Background()
-		parser := mocks.NewMockParser(ctrl)
+		mockParser := mocks.NewMockParser(ctrl)
 
-		c := crawler.New(crawler.Config{Depth: 1}, parser)
+		c := crawler.New(crawler.Config{Depth: 1}, mockParser, mocks.NewMockRobots(ctrl), newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
 		err := c.Crawl(ctx, gfi.URL(), 2)
 		assert.Error(t, err)
 		assert.Equal(t, crawler.ErrExceedsDepth, err)
@@ -76,17 +124,89 @@ func TestCrawler_Crawl(t *testing.T) {
 		defer ctrl.Finish()
 
 		ctx := context.Background()
-		parser := mocks.NewMockParser(ctrl)
+		mockParser := mocks.NewMockParser(ctrl)
 
 		startURL := gfi.URL()
 		staticUrl := "https://example.com/image.jpeg"
 
-		parser.EXPECT().ExtractURLs(startURL).Return([]string{}, []string{staticUrl}, nil)
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), startURL).Return(&parser.Page{StaticURLs: []string{staticUrl}}, nil)
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Wait(ctx, startURL).Return(nil)
 
-		c := crawler.New(crawler.Config{Depth: 3}, parser)
+		c := crawler.New(crawler.Config{Depth: 3}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
 		err := c.Crawl(ctx, startURL, 1)
 		assert.NoError(t, err)
-		assert.Equal(t, map[string][]string{startURL: {staticUrl}}, c.SeenURLs())
+		assert.Equal(t, map[string][]string{startURL: {staticUrl}}, pageURLs(crawledPages(t, c)))
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		mockParser := mocks.NewMockParser(ctrl)
+
+		startURL := gfi.URL()
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), startURL).
+			Return(nil, &parser.StatusError{URL: startURL, StatusCode: http.StatusNotFound})
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Wait(ctx, startURL).Return(nil)
+
+		c := crawler.New(crawler.Config{Depth: 3}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+		err := c.Crawl(ctx, startURL, 1)
+		var statusErr *parser.StatusError
+		assert.ErrorAs(t, err, &statusErr)
+		result := crawledPages(t, c)[startURL]
+		assert.Equal(t, http.StatusNotFound, result.Status)
+		assert.Equal(t, 1, result.Depth)
+		assert.Contains(t, result.Error, "unexpected status 404")
+		assert.Empty(t, result.URLs)
+	})
+
+	t.Run("redirected to error status", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+
+		redirects := []parser.Redirect{{URL: "https://example.com/old", Status: 301, Location: "https://example.com/gone"}}
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/old").Return(nil, &parser.StatusError{
+			URL: "https://example.com/old", StatusCode: http.StatusNotFound,
+			FinalURL: "https://example.com/gone", Redirects: redirects,
+		})
+		robots.EXPECT().Wait(ctx, "https://example.com/old").Return(nil)
+
+		c := crawler.New(crawler.Config{Depth: 3}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+		assert.Error(t, c.Crawl(ctx, "https://example.com/old", 1))
+		result := crawledPages(t, c)["https://example.com/old"]
+		assert.Equal(t, http.StatusNotFound, result.Status)
+		assert.Equal(t, "https://example.com/gone", result.FinalURL)
+		assert.Equal(t, redirects, result.Redirects)
+	})
+
+	t.Run("redirected", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+
+		redirects := []parser.Redirect{{URL: "https://example.com/old", Status: 301, Location: "https://example.com/new"}}
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/old").
+			Return(&parser.Page{FinalURL: "https://example.com/new", Redirects: redirects, StatusCode: 200}, nil)
+		robots.EXPECT().Wait(ctx, "https://example.com/old").Return(nil)
+
+		c := crawler.New(crawler.Config{Depth: 3}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+		assert.NoError(t, c.Crawl(ctx, "https://example.com/old", 1))
+		// the final url is not crawled again
+		assert.NoError(t, c.Crawl(ctx, "https://example.com/new", 1))
+
+		result := crawledPages(t, c)["https://example.com/old"]
+		assert.Equal(t, "https://example.com/new", result.FinalURL)
+		assert.Equal(t, redirects, result.Redirects)
 	})
 
 	t.Run("url already seen", func(t *testing.T) {
@@ -94,12 +214,14 @@ func TestCrawler_Crawl(t *testing.T) {
 		defer ctrl.Finish()
 
 		ctx := context.Background()
-		parser := mocks.NewMockParser(ctrl)
+		mockParser := mocks.NewMockParser(ctrl)
 
 		startURL := gfi.URL()
-		parser.EXPECT().ExtractURLs(startURL).Return([]string{}, []string{}, nil).Times(1)
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), startURL).Return(&parser.Page{}, nil).Times(1)
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Wait(ctx, startURL).Return(nil).Times(1)
 
-		c := crawler.New(crawler.Config{Depth: 3}, parser)
+		c := crawler.New(crawler.Config{Depth: 3}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
 		err := c.Crawl(ctx, startURL, 2)
 		assert.NoError(t, err)
 
@@ -107,3 +229,506 @@ func TestCrawler_Crawl(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestCrawler_Robots(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockParser := mocks.NewMockParser(ctrl)
+	robots := mocks.NewMockRobots(ctrl)
+
+	startURL := "https://example.com"
+	adminURL := "https://example.com/admin"
+	staticURL := "https://example.com/image.jpeg"
+
+	robots.EXPECT().Wait(ctx, startURL).Return(nil)
+	mockParser.EXPECT().ExtractURLs(gomock.Any(), startURL).Return(&parser.Page{WebURLs: []string{adminURL}, StaticURLs: []string{staticURL}}, nil)
+	robots.EXPECT().Allowed(gomock.Any(), adminURL).Return(false, nil)
+
+	c := crawler.New(crawler.Config{Depth: 3}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+	err := c.Crawl(ctx, startURL, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]string{startURL: {staticURL}}, pageURLs(crawledPages(t, c)))
+	assert.Equal(t, map[string]crawler.SkipReason{adminURL: crawler.SkipReasonRobotsDisallowed}, c.SkippedURLs())
+}
+
+func TestCrawler_NoFollow(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockParser := mocks.NewMockParser(ctrl)
+	robots := mocks.NewMockRobots(ctrl)
+	robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+	robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com").Return(&parser.Page{
+		WebURLs:      []string{"https://example.com/a", "https://example.com/nofollow"},
+		NoFollowURLs: []string{"https://example.com/b", "https://example.com/c"},
+	}, nil)
+	// the nofollow link is crawled once it is found by a followed link
+	mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/a").
+		Return(&parser.Page{WebURLs: []string{"https://example.com/b"}}, nil)
+	mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/b").Return(&parser.Page{}, nil)
+	mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/nofollow").
+		Return(&parser.Page{WebURLs: []string{"https://example.com/d"}, NoFollow: true}, nil)
+
+	c := crawler.New(crawler.Config{WorkerCount: 1, Depth: 3}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+	assert.NoError(t, c.Run(ctx, "https://example.com"))
+	assert.Empty(t, c.Errors())
+	assert.Equal(t, map[string][]string{
+		"https://example.com": {
+			"https://example.com/a", "https://example.com/nofollow", "https://example.com/b", "https://example.com/c",
+		},
+		"https://example.com/a":        {"https://example.com/b"},
+		"https://example.com/b":        {},
+		"https://example.com/nofollow": {"https://example.com/d"},
+	}, pageURLs(crawledPages(t, c)))
+	assert.True(t, crawledPages(t, c)["https://example.com/nofollow"].NoFollow)
+	assert.Equal(t, map[string]crawler.SkipReason{
+		"https://example.com/c": crawler.SkipReasonNoFollow,
+		"https://example.com/d": crawler.SkipReasonNoFollow,
+	}, c.SkippedURLs())
+}
+
+func TestCrawler_Traps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	mockParser := mocks.NewMockParser(ctrl)
+	robots := mocks.NewMockRobots(ctrl)
+
+	startURL := "https://example.com"
+	pageURL := "https://example.com/calendar?month=1"
+	trapURLs := []string{
+		"https://example.com/calendar?month=2",
+		"https://example.com/a/a/a",
+	}
+	robots.EXPECT().Wait(ctx, startURL).Return(nil)
+	mockParser.EXPECT().ExtractURLs(gomock.Any(), startURL).
+		Return(&parser.Page{WebURLs: append([]string{pageURL}, trapURLs...)}, nil)
+	robots.EXPECT().Allowed(gomock.Any(), pageURL).Return(true, nil)
+
+	c := crawler.New(crawler.Config{Depth: 1, TrapMaxQueryVariants: 1, TrapMaxRepeatedSegments: 2}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+	assert.NoError(t, c.Crawl(ctx, startURL, 1))
+	skipped := c.SkippedURLs()
+	assert.Equal(t, map[string]crawler.SkipReason{
+		trapURLs[0]: crawler.SkipReasonTrapQueryVariants,
+		trapURLs[1]: crawler.SkipReasonTrapRepeatedSegments,
+	}, skipped)
+	assert.Equal(t, map[crawler.SkipReason]int{
+		crawler.SkipReasonTrapQueryVariants:    1,
+		crawler.SkipReasonTrapRepeatedSegments: 1,
+	}, crawler.TrapURLsCount(skipped))
+}
+
+func TestCrawler_Run(t *testing.T) {
+	pages := map[string][]string{
+		"https://example.com":   {"https://example.com/a", "https://example.com/b"},
+		"https://example.com/a": {"https://example.com/c"},
+		"https://example.com/b": {"https://example.com/c", "https://example.com"},
+		"https://example.com/c": {},
+	}
+
+	for name, tc := range map[string]struct {
+		config crawler.Config
+		// storage is the storage of both the frontier and the seen set
+		storage string
+	}{
+		"several workers": {config: crawler.Config{WorkerCount: 4, Depth: 10}, storage: frontier.StorageMemory},
+		"single worker":   {config: crawler.Config{WorkerCount: 1, Depth: 10}, storage: frontier.StorageMemory},
+		"disk storages":   {config: crawler.Config{WorkerCount: 4, Depth: 10}, storage: frontier.StorageDisk},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx := context.Background()
+			mockParser := mocks.NewMockParser(ctrl)
+			robots := mocks.NewMockRobots(ctrl)
+			robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+			robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			for u, children := range pages {
+				mockParser.EXPECT().ExtractURLs(gomock.Any(), u).Return(&parser.Page{WebURLs: children}, nil).Times(1)
+			}
+
+			f, err := frontier.New(frontier.Config{Storage: tc.storage, Dir: t.TempDir(), Order: frontier.OrderBFS})
+			require.NoError(t, err)
+			defer f.Close()
+			seenURLs, err := seen.New(seen.Config{Storage: tc.storage, Dir: t.TempDir()})
+			require.NoError(t, err)
+			defer seenURLs.Close()
+			pageResults, err := results.New(results.Config{Storage: tc.storage, Dir: t.TempDir()})
+			require.NoError(t, err)
+			defer pageResults.Close()
+
+			c := crawler.New(tc.config, mockParser, robots, f, seenURLs, pageResults, nil)
+			err = c.Run(ctx, "https://example.com")
+			assert.NoError(t, err)
+			assert.Empty(t, c.Errors())
+			assert.Len(t, pageURLs(crawledPages(t, c)), len(pages))
+		})
+	}
+
+	t.Run("shortest path depth", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		for u, children := range map[string][]string{
+			"https://example.com":     {"https://example.com/a", "https://example.com/b"},
+			"https://example.com/a":   {"https://example.com/a/1"},
+			"https://example.com/a/1": {"https://example.com/c"},
+			"https://example.com/b":   {"https://example.com/c"},
+			"https://example.com/c":   {},
+		} {
+			mockParser.EXPECT().ExtractURLs(gomock.Any(), u).Return(&parser.Page{WebURLs: children}, nil)
+		}
+
+		c := crawler.New(crawler.Config{WorkerCount: 1, Depth: 10}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+		assert.NoError(t, c.Run(ctx, "https://example.com"))
+		depths := make(map[string]int)
+		for u, r := range crawledPages(t, c) {
+			depths[u] = r.Depth
+		}
+		assert.Equal(t, map[string]int{
+			"https://example.com":     1,
+			"https://example.com/a":   2,
+			"https://example.com/b":   2,
+			"https://example.com/a/1": 3,
+			"https://example.com/c":   3,
+		}, depths)
+	})
+
+	t.Run("frontier is full", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com").
+			Return(&parser.Page{WebURLs: []string{"https://example.com/a", "https://example.com/b"}}, nil)
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/a").Return(&parser.Page{}, nil)
+
+		c := crawler.New(crawler.Config{WorkerCount: 1, Depth: 10}, mockParser, robots, newFrontier(t, 1), seen.NewMemory(), results.NewMemory(), nil)
+		assert.NoError(t, c.Run(ctx, "https://example.com"))
+		assert.Len(t, crawledPages(t, c), 2)
+		assert.Equal(t, map[string]crawler.SkipReason{
+			"https://example.com/b": crawler.SkipReasonFrontierFull,
+		}, c.SkippedURLs())
+	})
+
+	t.Run("errors are collected", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com").Return(&parser.Page{WebURLs: []string{"https://example.com/a"}}, nil)
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/a").Return(nil, fmt.Errorf("not found"))
+
+		c := crawler.New(crawler.Config{WorkerCount: 2, Depth: 10}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+		err := c.Run(ctx, "https://example.com")
+		assert.NoError(t, err)
+		assert.Len(t, c.Errors(), 1)
+	})
+
+	t.Run("seed disallowed by robots", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), "https://example.com").Return(false, nil)
+
+		c := crawler.New(crawler.Config{WorkerCount: 2, Depth: 10}, mocks.NewMockParser(ctrl), robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+		err := c.Run(context.Background(), "https://example.com")
+		assert.NoError(t, err)
+		assert.Empty(t, pageURLs(crawledPages(t, c)))
+		assert.Equal(t, map[string]crawler.SkipReason{
+			"https://example.com": crawler.SkipReasonRobotsDisallowed,
+		}, c.SkippedURLs())
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		c := crawler.New(crawler.Config{WorkerCount: 2, Depth: 10}, mocks.NewMockParser(ctrl), robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+		err := c.Run(ctx, "https://example.com")
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, c.Errors())
+	})
+}
+
+func TestCrawler_State(t *testing.T) {
+	t.Run("final state is saved", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+		store := mocks.NewMockStore(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com").Return(&parser.Page{WebURLs: []string{"https://example.com/a"}}, nil)
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/a").Return(&parser.Page{}, nil)
+		store.EXPECT().Save(gomock.Any()).DoAndReturn(func(s *crawler.State) error {
+			assert.Equal(t, map[string][]string{
+				"https://example.com":   {"https://example.com/a"},
+				"https://example.com/a": {},
+			}, pageURLs(s.Results))
+			assert.Equal(t, "https://example.com", s.Results["https://example.com/a"].Parent)
+			assert.Equal(t, 2, s.Results["https://example.com/a"].Depth)
+			assert.Empty(t, s.Frontier)
+			assert.Empty(t, s.Skipped)
+			return nil
+		})
+
+		c := crawler.New(crawler.Config{WorkerCount: 2, Depth: 10}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), store)
+		assert.NoError(t, c.Run(context.Background(), "https://example.com"))
+	})
+
+	t.Run("snapshots contain only changes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx := context.Background()
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com").
+			Return(&parser.Page{NoFollowURLs: []string{"https://example.com/a"}}, nil)
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/a").Return(&parser.Page{}, nil)
+
+		c := crawler.New(crawler.Config{Depth: 10}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), mocks.NewMockStore(ctrl))
+		assert.NoError(t, c.Crawl(ctx, "https://example.com", 1))
+		s, err := c.State()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{"https://example.com": {"https://example.com/a"}}, pageURLs(s.Results))
+		assert.Equal(t, map[string]crawler.SkipReason{"https://example.com/a": crawler.SkipReasonNoFollow}, s.Skipped)
+
+		// the nofollow link found by a followed link is not skipped anymore
+		assert.NoError(t, c.Crawl(ctx, "https://example.com/a", 2))
+		s, err = c.State()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{"https://example.com/a": {}}, pageURLs(s.Results))
+		assert.Equal(t, map[string]crawler.SkipReason{"https://example.com/a": ""}, s.Skipped)
+		assert.Len(t, crawledPages(t, c), 2)
+	})
+
+	t.Run("failed checkpoint is saved again", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+		store := mocks.NewMockStore(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com").Return(&parser.Page{}, nil)
+		store.EXPECT().Save(gomock.Any()).Return(fmt.Errorf("disk is full"))
+
+		c := crawler.New(crawler.Config{WorkerCount: 2, Depth: 10}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), store)
+		assert.NoError(t, c.Run(context.Background(), "https://example.com"))
+		assert.Len(t, c.Errors(), 1)
+		s, err := c.State()
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{"https://example.com": {}}, pageURLs(s.Results))
+	})
+
+	t.Run("restored frontier is crawled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockParser := mocks.NewMockParser(ctrl)
+		robots := mocks.NewMockRobots(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+		// the page from the frontier is crawled again even if it has been saved as visited
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/a").Return(&parser.Page{WebURLs: []string{"https://example.com/b"}}, nil)
+		mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com/b").Return(&parser.Page{}, nil)
+
+		c := crawler.New(crawler.Config{WorkerCount: 2, Depth: 10}, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+		assert.NoError(t, c.Restore(&crawler.State{
+			Frontier: []crawler.Job{{URL: "https://example.com/a", Depth: 2}},
+			Results: map[string]crawler.PageResult{
+				"https://example.com":   {URL: "https://example.com", URLs: []string{"https://example.com/a"}, Depth: 1},
+				"https://example.com/a": {URL: "https://example.com/a", URLs: []string{}, Depth: 2},
+			},
+			Errors: []string{"previous error"},
+		}))
+		assert.NoError(t, c.Run(context.Background(), "https://example.com"))
+		assert.Equal(t, map[string][]string{
+			"https://example.com":   {"https://example.com/a"},
+			"https://example.com/a": {"https://example.com/b"},
+			"https://example.com/b": {},
+		}, pageURLs(crawledPages(t, c)))
+		assert.Len(t, c.Errors(), 1)
+	})
+
+	t.Run("cancelled jobs stay in the frontier", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		robots := mocks.NewMockRobots(ctrl)
+		store := mocks.NewMockStore(ctrl)
+		robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+		store.EXPECT().Save(gomock.Any()).DoAndReturn(func(s *crawler.State) error {
+			assert.Equal(t, []crawler.Job{{URL: "https://example.com", Depth: 1}}, s.Frontier)
+			return nil
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		c := crawler.New(crawler.Config{WorkerCount: 2, Depth: 10}, mocks.NewMockParser(ctrl), robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), store)
+		assert.ErrorIs(t, c.Run(ctx, "https://example.com"), context.Canceled)
+	})
+}
+
+func TestCrawler_Shutdown(t *testing.T) {
+	for name, tc := range map[string]struct {
+		shutdownTimeout  time.Duration
+		expectedPages    map[string][]string
+		expectedFrontier []crawler.Job
+	}{
+		"page finishes within timeout": {
+			shutdownTimeout: time.Second,
+			expectedPages: map[string][]string{
+				"https://example.com": {"https://example.com/a"},
+			},
+			expectedFrontier: []crawler.Job{{URL: "https://example.com/a", Depth: 2, Parent: "https://example.com"}},
+		},
+		"page is aborted after timeout": {
+			shutdownTimeout:  0,
+			expectedPages:    map[string][]string{},
+			expectedFrontier: []crawler.Job{{URL: "https://example.com", Depth: 1}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			mockParser := mocks.NewMockParser(ctrl)
+			robots := mocks.NewMockRobots(ctrl)
+			store := mocks.NewMockStore(ctrl)
+			robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+			robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			mockParser.EXPECT().ExtractURLs(gomock.Any(), "https://example.com").DoAndReturn(
+				func(fetchCtx context.Context, _ string) (*parser.Page, error) {
+					// interrupt the crawl while the page is being fetched
+					cancel()
+					select {
+					case <-fetchCtx.Done():
+						return nil, fetchCtx.Err()
+					case <-time.After(50 * time.Millisecond):
+						return &parser.Page{WebURLs: []string{"https://example.com/a"}}, nil
+					}
+				},
+			)
+			store.EXPECT().Save(gomock.Any()).DoAndReturn(func(s *crawler.State) error {
+				assert.Equal(t, tc.expectedPages, pageURLs(s.Results))
+				// the aborted page and urls found on the finished one are crawled on resume
+				assert.ElementsMatch(t, tc.expectedFrontier, s.Frontier)
+				return nil
+			})
+
+			config := crawler.Config{WorkerCount: 1, Depth: 10, ShutdownTimeout: tc.shutdownTimeout}
+			c := crawler.New(config, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), store)
+			assert.ErrorIs(t, c.Run(ctx, "https://example.com"), context.Canceled)
+			assert.Empty(t, c.Errors())
+		})
+	}
+}
+
+func TestCrawler_Budgets(t *testing.T) {
+	// every page links to the next one, so pages are crawled one by one
+	pages := []string{"https://example.com", "https://example.com/1", "https://example.com/2", "https://example.com/3"}
+
+	for name, tc := range map[string]struct {
+		config           crawler.Config
+		delay            time.Duration
+		expectedBudget   crawler.Budget
+		expectedPages    int
+		expectedFrontier []crawler.Job
+	}{
+		"pages": {
+			config:           crawler.Config{MaxPages: 2},
+			expectedBudget:   crawler.BudgetPages,
+			expectedPages:    2,
+			expectedFrontier: []crawler.Job{{URL: "https://example.com/2", Depth: 3, Parent: "https://example.com/1"}},
+		},
+		"bytes": {
+			config:           crawler.Config{MaxBytes: 250},
+			expectedBudget:   crawler.BudgetBytes,
+			expectedPages:    3,
+			expectedFrontier: []crawler.Job{{URL: "https://example.com/3", Depth: 4, Parent: "https://example.com/2"}},
+		},
+		"duration": {
+			config:           crawler.Config{MaxDuration: 50 * time.Millisecond, ShutdownTimeout: time.Second},
+			delay:            100 * time.Millisecond,
+			expectedBudget:   crawler.BudgetDuration,
+			expectedPages:    1,
+			expectedFrontier: []crawler.Job{{URL: "https://example.com/1", Depth: 2, Parent: "https://example.com"}},
+		},
+		"not reached": {
+			config:        crawler.Config{MaxPages: 4, MaxBytes: 1000, MaxDuration: time.Minute},
+			expectedPages: 4,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockParser := mocks.NewMockParser(ctrl)
+			robots := mocks.NewMockRobots(ctrl)
+			robots.EXPECT().Allowed(gomock.Any(), gomock.Any()).Return(true, nil).AnyTimes()
+			robots.EXPECT().Wait(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			for i, u := range pages {
+				page := &parser.Page{Size: 100}
+				if i+1 < len(pages) {
+					page.WebURLs = []string{pages[i+1]}
+				}
+				mockParser.EXPECT().ExtractURLs(gomock.Any(), u).DoAndReturn(func(context.Context, string) (*parser.Page, error) {
+					time.Sleep(tc.delay)
+					return page, nil
+				}).MaxTimes(1)
+			}
+
+			tc.config.WorkerCount, tc.config.Depth = 1, 10
+			c := crawler.New(tc.config, mockParser, robots, newFrontier(t, 0), seen.NewMemory(), results.NewMemory(), nil)
+			err := c.Run(context.Background(), pages[0])
+			if tc.expectedBudget == "" {
+				assert.NoError(t, err)
+			} else {
+				var budgetErr *crawler.BudgetError
+				assert.ErrorAs(t, err, &budgetErr)
+				assert.Equal(t, tc.expectedBudget, budgetErr.Budget)
+			}
+			assert.Len(t, crawledPages(t, c), tc.expectedPages)
+			// pages that were not fetched stay in the frontier, the same way as on interruption
+			s, err := c.State()
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tc.expectedFrontier, s.Frontier)
+			assert.Empty(t, c.Errors())
+		})
+	}
+}
@@ -2,4 +2,51 @@ package crawler
 
 import "fmt"
 
-var ErrExceedsDepth = fmt.Errorf("crawler exceeds depth")
+var (
+	ErrExceedsDepth = fmt.Errorf("crawler exceeds depth")
+	ErrStopped      = fmt.Errorf("crawler is stopped")
+)
+
+// SkipReason explains why found url was not crawled.
+type SkipReason string
+
+const (
+	SkipReasonRobotsDisallowed SkipReason = "disallowed by robots.txt"
+	SkipReasonNoFollow         SkipReason = "nofollow link"
+	SkipReasonFrontierFull     SkipReason = "frontier is full"
+
+	SkipReasonTrapPathDepth        SkipReason = "crawler trap: path is too deep"
+	SkipReasonTrapRepeatedSegments SkipReason = "crawler trap: path segment is repeated too many times"
+	SkipReasonTrapQueryVariants    SkipReason = "crawler trap: too many query variants of the path"
+	SkipReasonTrapURLLength        SkipReason = "crawler trap: url is too long"
+)
+
+// IsTrap checks if the url was skipped as a crawler trap.
+func (r SkipReason) IsTrap() bool {
+	switch r {
+	case SkipReasonTrapPathDepth, SkipReasonTrapRepeatedSegments, SkipReasonTrapQueryVariants, SkipReasonTrapURLLength:
+		return true
+	default:
+		return false
+	}
+}
+
+// Budget is a limit of the crawl that stops it once reached.
+type Budget string
+
+const (
+	BudgetPages    Budget = "pages"
+	BudgetDuration Budget = "duration"
+	BudgetBytes    Budget = "bytes"
+)
+
+// BudgetError is returned by Run when the crawl is stopped because one of its budgets is reached.
+type BudgetError struct {
+	Budget Budget
+	// Limit is the configured value of the budget
+	Limit string
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("crawl %s budget of %s is reached", e.Budget, e.Limit)
+}
@@ -0,0 +1,82 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/triabokon/goscout/internal/crawler (interfaces: Frontier)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	crawler "github.com/triabokon/goscout/internal/crawler"
+)
+
+// MockFrontier is a mock of Frontier interface.
+type MockFrontier struct {
+	ctrl     *gomock.Controller
+	recorder *MockFrontierMockRecorder
+}
+
+// MockFrontierMockRecorder is the mock recorder for MockFrontier.
+type MockFrontierMockRecorder struct {
+	mock *MockFrontier
+}
+
+// NewMockFrontier creates a new mock instance.
+func NewMockFrontier(ctrl *gomock.Controller) *MockFrontier {
+	mock := &MockFrontier{ctrl: ctrl}
+	mock.recorder = &MockFrontierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFrontier) EXPECT() *MockFrontierMockRecorder {
+	return m.recorder
+}
+
+// Jobs mocks base method.
+func (m *MockFrontier) Jobs() ([]crawler.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Jobs")
+	ret0, _ := ret[0].([]crawler.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Jobs indicates an expected call of Jobs.
+func (mr *MockFrontierMockRecorder) Jobs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Jobs", reflect.TypeOf((*MockFrontier)(nil).Jobs))
+}
+
+// Pop mocks base method.
+func (m *MockFrontier) Pop() (crawler.Job, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Pop")
+	ret0, _ := ret[0].(crawler.Job)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Pop indicates an expected call of Pop.
+func (mr *MockFrontierMockRecorder) Pop() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Pop", reflect.TypeOf((*MockFrontier)(nil).Pop))
+}
+
+// Push mocks base method.
+func (m *MockFrontier) Push(arg0 crawler.Job) (crawler.Job, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Push", arg0)
+	ret0, _ := ret[0].(crawler.Job)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Push indicates an expected call of Push.
+func (mr *MockFrontierMockRecorder) Push(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Push", reflect.TypeOf((*MockFrontier)(nil).Push), arg0)
+}
@@ -5,9 +5,11 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	parser "github.com/triabokon/goscout/internal/parser"
 )
 
 // MockParser is a mock of Parser interface.
@@ -34,17 +36,16 @@ func (m *MockParser) EXPECT() *MockParserMockRecorder {
 }
 
 // ExtractURLs mocks base method.
-func (m *MockParser) ExtractURLs(arg0 string) ([]string, []string, error) {
+func (m *MockParser) ExtractURLs(arg0 context.Context, arg1 string) (*parser.Page, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExtractURLs", arg0)
-	ret0, _ := ret[0].([]string)
-	ret1, _ := ret[1].([]string)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "ExtractURLs", arg0, arg1)
+	ret0, _ := ret[0].(*parser.Page)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // ExtractURLs indicates an expected call of ExtractURLs.
-func (mr *MockParserMockRecorder) ExtractURLs(arg0 interface{}) *gomock.Call {
+func (mr *MockParserMockRecorder) ExtractURLs(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractURLs", reflect.TypeOf((*MockParser)(nil).ExtractURLs), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractURLs", reflect.TypeOf((*MockParser)(nil).ExtractURLs), arg0, arg1)
 }
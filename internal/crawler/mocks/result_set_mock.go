@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/triabokon/goscout/internal/crawler (interfaces: ResultSet)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	crawler "github.com/triabokon/goscout/internal/crawler"
+)
+
+// MockResultSet is a mock of ResultSet interface.
+type MockResultSet struct {
+	ctrl     *gomock.Controller
+	recorder *MockResultSetMockRecorder
+}
+
+// MockResultSetMockRecorder is the mock recorder for MockResultSet.
+type MockResultSetMockRecorder struct {
+	mock *MockResultSet
+}
+
+// NewMockResultSet creates a new mock instance.
+func NewMockResultSet(ctrl *gomock.Controller) *MockResultSet {
+	mock := &MockResultSet{ctrl: ctrl}
+	mock.recorder = &MockResultSetMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockResultSet) EXPECT() *MockResultSetMockRecorder {
+	return m.recorder
+}
+
+// Len mocks base method.
+func (m *MockResultSet) Len() (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Len")
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Len indicates an expected call of Len.
+func (mr *MockResultSetMockRecorder) Len() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Len", reflect.TypeOf((*MockResultSet)(nil).Len))
+}
+
+// Put mocks base method.
+func (m *MockResultSet) Put(arg0 crawler.PageResult) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockResultSetMockRecorder) Put(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockResultSet)(nil).Put), arg0)
+}
+
+// Range mocks base method.
+func (m *MockResultSet) Range(arg0 func(crawler.PageResult) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Range", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Range indicates an expected call of Range.
+func (mr *MockResultSetMockRecorder) Range(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Range", reflect.TypeOf((*MockResultSet)(nil).Range), arg0)
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/triabokon/goscout/internal/crawler (interfaces: Robots)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockRobots is a mock of Robots interface.
+type MockRobots struct {
+	ctrl     *gomock.Controller
+	recorder *MockRobotsMockRecorder
+}
+
+// MockRobotsMockRecorder is the mock recorder for MockRobots.
+type MockRobotsMockRecorder struct {
+	mock *MockRobots
+}
+
+// NewMockRobots creates a new mock instance.
+func NewMockRobots(ctrl *gomock.Controller) *MockRobots {
+	mock := &MockRobots{ctrl: ctrl}
+	mock.recorder = &MockRobotsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRobots) EXPECT() *MockRobotsMockRecorder {
+	return m.recorder
+}
+
+// Allowed mocks base method.
+func (m *MockRobots) Allowed(arg0 context.Context, arg1 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allowed", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Allowed indicates an expected call of Allowed.
+func (mr *MockRobotsMockRecorder) Allowed(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allowed", reflect.TypeOf((*MockRobots)(nil).Allowed), arg0, arg1)
+}
+
+// Wait mocks base method.
+func (m *MockRobots) Wait(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Wait", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Wait indicates an expected call of Wait.
+func (mr *MockRobotsMockRecorder) Wait(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Wait", reflect.TypeOf((*MockRobots)(nil).Wait), arg0, arg1)
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/triabokon/goscout/internal/crawler (interfaces: SeenSet)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockSeenSet is a mock of SeenSet interface.
+type MockSeenSet struct {
+	ctrl     *gomock.Controller
+	recorder *MockSeenSetMockRecorder
+}
+
+// MockSeenSetMockRecorder is the mock recorder for MockSeenSet.
+type MockSeenSetMockRecorder struct {
+	mock *MockSeenSet
+}
+
+// NewMockSeenSet creates a new mock instance.
+func NewMockSeenSet(ctrl *gomock.Controller) *MockSeenSet {
+	mock := &MockSeenSet{ctrl: ctrl}
+	mock.recorder = &MockSeenSetMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSeenSet) EXPECT() *MockSeenSetMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockSeenSet) Add(arg0 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockSeenSetMockRecorder) Add(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockSeenSet)(nil).Add), arg0)
+}
+
+// Contains mocks base method.
+func (m *MockSeenSet) Contains(arg0 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Contains", arg0)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Contains indicates an expected call of Contains.
+func (mr *MockSeenSetMockRecorder) Contains(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Contains", reflect.TypeOf((*MockSeenSet)(nil).Contains), arg0)
+}
@@ -0,0 +1,135 @@
+package crawler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+//go:generate mockgen -destination=./mocks/store_mock.go -package=mocks github.com/triabokon/goscout/internal/crawler Store
+type Store interface {
+	Save(s *State) error
+}
+
+// State is a snapshot of the crawl progress that could be saved and restored later.
+// Results and skipped urls are only changes since the previous snapshot, so the state is saved incrementally.
+type State struct {
+	// Frontier contains jobs that were queued or being crawled
+	Frontier []Job
+	// Results contains results of pages crawled since the previous snapshot by their urls
+	Results map[string]PageResult
+	// Skipped contains skip reasons changed since the previous snapshot, empty reason means the url is not skipped
+	Skipped map[string]SkipReason
+	Errors  []string
+}
+
+// State returns a consistent snapshot of the crawl progress with changes since the previous snapshot,
+// changes are tracked only if the crawler has the store.
+func (c *Crawler) State() (*State, error) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+
+	c.mu.Lock()
+	jobs, err := c.frontier.Jobs()
+	c.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get frontier jobs: %w", err)
+	}
+	s := &State{
+		Frontier: jobs,
+		Results:  takeResults(c.unsavedResults),
+		// skip reasons are changed without the state lock, the reason changed after it was taken is left unsaved
+		Skipped: takeSkippedURLs(c.unsavedSkipped),
+	}
+	c.inFlight.Range(func(_, value interface{}) bool {
+		if j, ok := value.(Job); ok {
+			s.Frontier = append(s.Frontier, j)
+		}
+		return true
+	})
+	for _, e := range c.Errors() {
+		s.Errors = append(s.Errors, e.Error())
+	}
+	return s, nil
+}
+
+// Restore loads previously saved state, its frontier is crawled on the next Run.
+// Results of the state are added to the result set, which could already contain restored results as well,
+// pages of the result set are marked as seen, so they are not crawled again.
+func (c *Crawler) Restore(s *State) error {
+	for _, r := range s.Results {
+		if err := c.results.Put(r); err != nil {
+			return fmt.Errorf("failed to store page result: %w", err)
+		}
+	}
+	// a page from the frontier could have scheduled only part of its urls before the state was saved,
+	// so it is not marked as seen to be crawled again
+	frontier := make(map[string]struct{}, len(s.Frontier))
+	for _, j := range s.Frontier {
+		frontier[j.URL] = struct{}{}
+	}
+	err := c.results.Range(func(r PageResult) error {
+		for _, seenURL := range []string{r.URL, r.FinalURL} {
+			if _, ok := frontier[seenURL]; ok || seenURL == "" {
+				continue
+			}
+			if _, err := c.seen.Add(seenURL); err != nil {
+				return fmt.Errorf("failed to mark url as seen: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for u, reason := range s.Skipped {
+		if reason != "" {
+			c.skippedURLs.Store(u, reason)
+		}
+	}
+	for _, e := range s.Errors {
+		c.errors = append(c.errors, errors.New(e))
+	}
+	c.restored = append(c.restored, s.Frontier...)
+	return nil
+}
+
+// checkpoints periodically saves the crawl state to the store until stop is closed.
+func (c *Crawler) checkpoints(stop <-chan struct{}) {
+	if c.store == nil || c.config.CheckpointInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.config.CheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			c.checkpoint()
+		}
+	}
+}
+
+func (c *Crawler) checkpoint() {
+	s, err := c.State()
+	if err != nil {
+		c.errc <- fmt.Errorf("failed to save checkpoint: %w", err)
+		return
+	}
+	if err = c.store.Save(s); err != nil {
+		c.unsave(s)
+		c.errc <- fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+}
+
+// unsave returns changes of the state that failed to save, so they are saved by the next checkpoint,
+// changes made after the state was taken are newer, so they are kept.
+func (c *Crawler) unsave(s *State) {
+	for u, r := range s.Results {
+		c.unsavedResults.LoadOrStore(u, r)
+	}
+	for u, reason := range s.Skipped {
+		c.unsavedSkipped.LoadOrStore(u, reason)
+	}
+}
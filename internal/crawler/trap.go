@@ -0,0 +1,92 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// maxTrapPaths is the number of paths which query variants are tracked, they are forgotten once it is reached,
+// so the detector does not grow with the site, while query variants of a trap are found close to each other.
+const maxTrapPaths = 100000
+
+// trapDetector detects urls of crawler traps, such as calendars, faceted filters and session ids,
+// which produce endless unique urls.
+type trapDetector struct {
+	config Config
+	mu     *sync.Mutex
+	// queries maps url without query to hashes of distinct queries of the url that were allowed,
+	// it stops growing once the limit of query variants is reached
+	queries map[string]map[uint64]struct{}
+}
+
+func newTrapDetector(c Config) *trapDetector {
+	return &trapDetector{
+		config:  c,
+		mu:      &sync.Mutex{},
+		queries: make(map[string]map[uint64]struct{}),
+	}
+}
+
+// check returns the reason to skip the url if it looks like a crawler trap, empty reason if it does not.
+func (d *trapDetector) check(u string) SkipReason {
+	if d.config.TrapMaxURLLength > 0 && len(u) > d.config.TrapMaxURLLength {
+		return SkipReasonTrapURLLength
+	}
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+	segments := strings.FieldsFunc(parsedURL.Path, func(r rune) bool { return r == '/' })
+	if d.config.TrapMaxPathDepth > 0 && len(segments) > d.config.TrapMaxPathDepth {
+		return SkipReasonTrapPathDepth
+	}
+	if d.config.TrapMaxRepeatedSegments > 0 && maxRepeats(segments) > d.config.TrapMaxRepeatedSegments {
+		return SkipReasonTrapRepeatedSegments
+	}
+	if d.config.TrapMaxQueryVariants > 0 && parsedURL.RawQuery != "" && !d.allowQuery(parsedURL) {
+		return SkipReasonTrapQueryVariants
+	}
+	return ""
+}
+
+// allowQuery records the query variant of the url path, the same variant is always allowed.
+func (d *trapDetector) allowQuery(u *url.URL) bool {
+	key := *u
+	key.RawQuery, key.Fragment, key.RawFragment = "", "", ""
+	h := fnv.New64a()
+	h.Write([]byte(u.RawQuery))
+	query := h.Sum64()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	variants, ok := d.queries[key.String()]
+	if !ok {
+		if len(d.queries) >= maxTrapPaths {
+			d.queries = make(map[string]map[uint64]struct{})
+		}
+		variants = make(map[uint64]struct{})
+		d.queries[key.String()] = variants
+	}
+	if _, ok = variants[query]; ok {
+		return true
+	}
+	if len(variants) >= d.config.TrapMaxQueryVariants {
+		return false
+	}
+	variants[query] = struct{}{}
+	return true
+}
+
+// maxRepeats returns the number of times the most repeated segment occurs in the path.
+func maxRepeats(segments []string) int {
+	counts := make(map[string]int, len(segments))
+	result := 0
+	for _, s := range segments {
+		counts[s]++
+		if counts[s] > result {
+			result = counts[s]
+		}
+	}
+	return result
+}
@@ -0,0 +1,75 @@
+package crawler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrapDetector_Check(t *testing.T) {
+	config := Config{
+		TrapMaxPathDepth:        5,
+		TrapMaxRepeatedSegments: 2,
+		TrapMaxQueryVariants:    3,
+		TrapMaxURLLength:        100,
+	}
+
+	testCases := []struct {
+		name     string
+		config   Config
+		url      string
+		expected SkipReason
+	}{
+		{name: "regular url", config: config, url: "https://example.com/blog/2023/05/post"},
+		{
+			name:     "too long url",
+			config:   config,
+			url:      "https://example.com/?q=" + strings.Repeat("a", 100),
+			expected: SkipReasonTrapURLLength,
+		},
+		{
+			name:     "too deep path",
+			config:   config,
+			url:      "https://example.com/a/b/c/d/e/f",
+			expected: SkipReasonTrapPathDepth,
+		},
+		{
+			name:     "repeated segments",
+			config:   config,
+			url:      "https://example.com/en/shop/en/shop/en/",
+			expected: SkipReasonTrapRepeatedSegments,
+		},
+		{
+			name: "disabled limits",
+			url:  "https://example.com/a/a/a/a/a/a/a/a?q=" + strings.Repeat("a", 100),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, newTrapDetector(tc.config).check(tc.url))
+		})
+	}
+
+	t.Run("query variants", func(t *testing.T) {
+		d := newTrapDetector(config)
+		for i := 0; i < 3; i++ {
+			assert.Empty(t, d.check(fmt.Sprintf("https://example.com/calendar?day=%d", i)))
+		}
+		assert.Equal(t, SkipReasonTrapQueryVariants, d.check("https://example.com/calendar?day=3"))
+		// already allowed variant and other paths are not affected
+		assert.Empty(t, d.check("https://example.com/calendar?day=0"))
+		assert.Empty(t, d.check("https://example.com/calendar"))
+		assert.Empty(t, d.check("https://example.com/search?q=3"))
+	})
+
+	t.Run("tracked paths are bounded", func(t *testing.T) {
+		d := newTrapDetector(config)
+		for i := 0; i < maxTrapPaths+1; i++ {
+			assert.Empty(t, d.check(fmt.Sprintf("https://example.com/%d?q=1", i)))
+		}
+		assert.Len(t, d.queries, 1)
+	})
+}
@@ -74,10 +74,14 @@ func isTextURL(u string) (bool, error) {
 }
 
 // filterWebURLs filters visited web urls and urls that has wrong type.
-func filterWebURLs(urls []string, seenURLs *sync.Map) ([]string, error) {
+func filterWebURLs(urls []string, seen SeenSet) ([]string, error) {
 	filtered := make([]string, 0, len(urls))
 	for _, u := range unique(urls) {
-		if _, ok := seenURLs.Load(u); ok {
+		ok, err := seen.Contains(u)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check seen url: %w", err)
+		}
+		if ok {
 			continue
 		}
 		textLink, err := isTextURL(u)
@@ -106,12 +110,42 @@ func filterStaticURLs(urls []string) ([]string, error) {
 	return filtered, nil
 }
 
-func seenURLsToMap(seenURLs *sync.Map) map[string][]string {
-	result := make(map[string][]string)
-	seenURLs.Range(func(key, value interface{}) bool {
+// takeResults removes results from the map, returning them by their urls.
+func takeResults(results *sync.Map) map[string]PageResult {
+	result := make(map[string]PageResult)
+	results.Range(func(key, _ interface{}) bool {
+		value, loaded := results.LoadAndDelete(key)
+		if strKey, ok := key.(string); ok && loaded {
+			if r, ok := value.(PageResult); ok {
+				result[strKey] = r
+			}
+		}
+		return true
+	})
+	return result
+}
+
+// takeSkippedURLs removes skipped urls from the map, returning them mapped to the skip reason.
+func takeSkippedURLs(skippedURLs *sync.Map) map[string]SkipReason {
+	result := make(map[string]SkipReason)
+	skippedURLs.Range(func(key, _ interface{}) bool {
+		value, loaded := skippedURLs.LoadAndDelete(key)
+		if strKey, ok := key.(string); ok && loaded {
+			if reason, ok := value.(SkipReason); ok {
+				result[strKey] = reason
+			}
+		}
+		return true
+	})
+	return result
+}
+
+func skippedURLsToMap(skippedURLs *sync.Map) map[string]SkipReason {
+	result := make(map[string]SkipReason)
+	skippedURLs.Range(func(key, value interface{}) bool {
 		if strKey, ok := key.(string); ok {
-			if strSlice, ok := value.([]string); ok {
-				result[strKey] = strSlice
+			if reason, ok := value.(SkipReason); ok {
+				result[strKey] = reason
 			}
 		}
 		return true
@@ -119,12 +153,53 @@ func seenURLsToMap(seenURLs *sync.Map) map[string][]string {
 	return result
 }
 
-func TotalUniqueURLsCount(seenURLs map[string][]string) int {
-	au := make([]string, 0, len(seenURLs))
-	for k, v := range seenURLs {
-		au = append(au, k)
-		au = append(au, v...)
+// Summary contains numbers of crawled pages.
+type Summary struct {
+	Pages int
+	// UniqueURLs is the number of distinct urls of crawled pages and urls found on them
+	UniqueURLs int
+	// Retried is the number of pages that were fetched with more than one attempt
+	Retried int
+	// Truncated is the number of pages which body was larger than the max body size
+	Truncated int
+}
+
+// Summarize counts crawled pages of the result set in a single pass,
+// distinct urls are counted by adding them to the empty seen set, so they are not kept in memory.
+func Summarize(results ResultSet, urls SeenSet) (*Summary, error) {
+	s := &Summary{}
+	err := results.Range(func(r PageResult) error {
+		s.Pages++
+		if r.Attempts > 1 {
+			s.Retried++
+		}
+		if r.Truncated {
+			s.Truncated++
+		}
+		for _, u := range append([]string{r.URL}, r.URLs...) {
+			added, err := urls.Add(u)
+			if err != nil {
+				return fmt.Errorf("failed to add url: %w", err)
+			}
+			if added {
+				s.UniqueURLs++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize results: %w", err)
+	}
+	return s, nil
+}
+
+// TrapURLsCount returns number of urls skipped as crawler traps by the skip reason.
+func TrapURLsCount(skipped map[string]SkipReason) map[SkipReason]int {
+	counts := make(map[SkipReason]int)
+	for _, reason := range skipped {
+		if reason.IsTrap() {
+			counts[reason]++
+		}
 	}
-	uu := unique(au)
-	return len(uu)
+	return counts
 }
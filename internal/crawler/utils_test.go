@@ -2,7 +2,6 @@ package crawler
 
 import (
 	"mime"
-	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -140,36 +139,52 @@ func TestCrawlerUtils_IsTextURL(t *testing.T) {
 	}
 }
 
+// seenMap is a seen set that is not safe for concurrent use.
+type seenMap map[string]struct{}
+
+func (s seenMap) Add(u string) (bool, error) {
+	if _, ok := s[u]; ok {
+		return false, nil
+	}
+	s[u] = struct{}{}
+	return true, nil
+}
+
+func (s seenMap) Contains(u string) (bool, error) {
+	_, ok := s[u]
+	return ok, nil
+}
+
 func TestCrawlerUtils_FilterWebURLs(t *testing.T) {
 	testCases := []struct {
 		name           string
 		urls           []string
-		seenURLs       func(s *sync.Map, url string)
+		seenURLs       func(s seenMap, url string)
 		expectedResult []string
 	}{
 		{
 			name:           "unique web urls",
 			urls:           []string{"https://example.com/someurl", "https://example.com/someurl1.htm"},
-			seenURLs:       func(s *sync.Map, url string) {},
+			seenURLs:       func(s seenMap, url string) {},
 			expectedResult: []string{"https://example.com/someurl", "https://example.com/someurl1.htm"},
 		},
 		{
 			name:           "some static urls",
 			urls:           []string{"https://example.com/script.js", "https://example.com/someurl"},
-			seenURLs:       func(s *sync.Map, url string) {},
+			seenURLs:       func(s seenMap, url string) {},
 			expectedResult: []string{"https://example.com/someurl"},
 		},
 		{
 			name:           "duplicated text urls",
 			urls:           []string{"https://example.com/someurl", "https://example.com/someurl"},
-			seenURLs:       func(s *sync.Map, url string) {},
+			seenURLs:       func(s seenMap, url string) {},
 			expectedResult: []string{"https://example.com/someurl"},
 		},
 		{
 			name: "seen html urls",
 			urls: []string{"https://example.com/file1.html", "https://example.com/file2.html"},
-			seenURLs: func(s *sync.Map, url string) {
-				s.Store(url, nil)
+			seenURLs: func(s seenMap, url string) {
+				s[url] = struct{}{}
 			},
 			expectedResult: []string{},
 		},
@@ -177,7 +192,7 @@ func TestCrawlerUtils_FilterWebURLs(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			s := &sync.Map{}
+			s := seenMap{}
 			for _, u := range tc.urls {
 				tc.seenURLs(s, u)
 			}
@@ -219,3 +234,34 @@ func TestCrawlerUtils_FilterStaticURLs(t *testing.T) {
 		})
 	}
 }
+
+// resultList is a result set of results in their order that is not safe for concurrent use.
+type resultList []PageResult
+
+func (l *resultList) Put(r PageResult) error {
+	*l = append(*l, r)
+	return nil
+}
+
+func (l *resultList) Range(f func(r PageResult) error) error {
+	for _, r := range *l {
+		if err := f(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *resultList) Len() (int, error) {
+	return len(*l), nil
+}
+
+func TestCrawlerUtils_Summarize(t *testing.T) {
+	pages := &resultList{
+		{URL: "https://example.com", Attempts: 1, URLs: []string{"https://example.com/a", "https://example.com/logo.png"}},
+		{URL: "https://example.com/a", Attempts: 3, Truncated: true, URLs: []string{"https://example.com", "https://example.com/b"}},
+	}
+	summary, err := Summarize(pages, seenMap{})
+	assert.NoError(t, err)
+	assert.Equal(t, &Summary{Pages: 2, UniqueURLs: 4, Retried: 1, Truncated: 1}, summary)
+}
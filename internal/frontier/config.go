@@ -0,0 +1,56 @@
+package frontier
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+// Orders of jobs in the frontier, jobs of the same priority are crawled breadth-first in any order.
+const (
+	// OrderBFS crawls shallower pages first.
+	OrderBFS = "bfs"
+	// OrderInlinks crawls pages linked from more pages first.
+	OrderInlinks = "inlinks"
+	// OrderPattern crawls pages matching patterns with higher priority first.
+	OrderPattern = "pattern"
+)
+
+// Storages of the frontier.
+const (
+	// StorageMemory keeps jobs in memory.
+	StorageMemory = "memory"
+	// StorageDisk keeps jobs in a temporary database on disk.
+	StorageDisk = "disk"
+)
+
+type Config struct {
+	Storage string
+	// Dir is the directory of the disk storage, the system temporary directory is used if empty
+	Dir   string
+	Order string
+	// Patterns are "<regexp> <priority>" pairs, urls get the priority of the first matching pattern or zero
+	Patterns []string
+	// MaxSize is the maximum number of queued jobs, zero disables the limit
+	MaxSize int
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "FrontierConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.StringVar(&c.Storage, "storage", StorageMemory, "storage of pages waiting to be crawled: memory or disk")
+	f.StringVar(&c.Dir, "dir", "", "directory of the disk storage, the system temporary directory if empty")
+	f.StringVar(&c.Order, "order", OrderBFS, "order of pages to crawl: bfs, inlinks or pattern")
+	f.StringArrayVar(
+		&c.Patterns, "pattern", nil,
+		`"<regexp> <priority>" pair, urls matching the regexp are crawled before urls of lower priority with pattern order`,
+	)
+	f.IntVar(
+		&c.MaxSize, "max_size",
+		1000000, "maximum number of pages waiting to be crawled, pages of the lowest priority are skipped "+
+			"once it is reached, 0 disables the limit",
+	)
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
@@ -0,0 +1,196 @@
+package frontier
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/triabokon/goscout/internal/boltdb"
+	"github.com/triabokon/goscout/internal/crawler"
+)
+
+const (
+	// bucketEntries maps urls to their entries, bucketQueue maps ordering keys of entries to their urls
+	bucketEntries = "entries"
+	bucketQueue   = "queue"
+)
+
+// Disk keeps jobs waiting to be crawled in a temporary database and returns them in the configured order,
+// the same way as Memory does, so the frontier is limited by disk space rather than memory.
+// The database is not synced to disk, since the frontier is restored from the crawl state on resume,
+// and it is removed on Close. It is not safe for concurrent use, the crawler guards it.
+type Disk struct {
+	config   Config
+	ordering *ordering
+	db       *boltdb.Temp
+	size     int
+	seq      uint64
+}
+
+// NewDisk creates the database in the configured directory, the system temporary directory is used if it is empty.
+func NewDisk(c Config) (*Disk, error) {
+	o, err := newOrdering(c)
+	if err != nil {
+		return nil, err
+	}
+	db, err := boltdb.OpenTemp(c.Dir, "frontier-*.db", bucketEntries, bucketQueue)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open frontier database: %w", err)
+	}
+	return &Disk{config: c, ordering: o, db: db}, nil
+}
+
+// Push queues the job, if its url is already queued, the shorter path to it is kept.
+// If the frontier is full, the job of the lowest priority is dropped and returned, it could be the pushed one.
+func (d *Disk) Push(j crawler.Job) (crawler.Job, bool, error) {
+	var dropped *crawler.Job
+	added := 0
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		entries, queue := tx.Bucket([]byte(bucketEntries)), tx.Bucket([]byte(bucketQueue))
+		queued, err := getEntry(entries, j.URL)
+		if err != nil {
+			return err
+		}
+		if queued != nil {
+			if dErr := queue.Delete(d.ordering.key(queued)); dErr != nil {
+				return fmt.Errorf("failed to delete queue key: %w", dErr)
+			}
+			queued.update(j)
+			return d.put(entries, queue, queued)
+		}
+		e := &entry{Job: j, Inlinks: 1, Priority: d.ordering.priority(j.URL), Seq: d.seq}
+		d.seq++
+		if d.config.MaxSize > 0 && d.size >= d.config.MaxSize {
+			worst, wErr := d.last(entries, queue)
+			if wErr != nil {
+				return wErr
+			}
+			if !d.ordering.before(e, worst) {
+				dropped = &j
+				return nil
+			}
+			if dErr := d.delete(entries, queue, worst); dErr != nil {
+				return dErr
+			}
+			dropped = &worst.Job
+			added--
+		}
+		added++
+		return d.put(entries, queue, e)
+	})
+	if err != nil {
+		return crawler.Job{}, false, fmt.Errorf("failed to push job: %w", err)
+	}
+	d.size += added
+	if dropped == nil {
+		return crawler.Job{}, false, nil
+	}
+	return *dropped, true, nil
+}
+
+// Pop returns the next job to crawl, it returns false if the frontier is empty.
+func (d *Disk) Pop() (crawler.Job, bool, error) {
+	var next *entry
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		entries, queue := tx.Bucket([]byte(bucketEntries)), tx.Bucket([]byte(bucketQueue))
+		_, u := queue.Cursor().First()
+		if u == nil {
+			return nil
+		}
+		e, err := getEntry(entries, string(u))
+		if err != nil {
+			return err
+		}
+		if e == nil {
+			return fmt.Errorf("queued url %s has no entry", u)
+		}
+		next = e
+		return d.delete(entries, queue, e)
+	})
+	if err != nil {
+		return crawler.Job{}, false, fmt.Errorf("failed to pop job: %w", err)
+	}
+	if next == nil {
+		return crawler.Job{}, false, nil
+	}
+	d.size--
+	return next.Job, true, nil
+}
+
+// Jobs returns all queued jobs in no particular order.
+func (d *Disk) Jobs() ([]crawler.Job, error) {
+	jobs := make([]crawler.Job, 0, d.size)
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketEntries)).ForEach(func(_, v []byte) error {
+			var e entry
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("failed to unmarshal entry: %w", err)
+			}
+			jobs = append(jobs, e.Job)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// Close closes and removes the database.
+func (d *Disk) Close() error {
+	if err := d.db.Close(); err != nil {
+		return fmt.Errorf("failed to close frontier database: %w", err)
+	}
+	return nil
+}
+
+// last returns the entry of the lowest priority.
+func (d *Disk) last(entries, queue *bbolt.Bucket) (*entry, error) {
+	_, u := queue.Cursor().Last()
+	e, err := getEntry(entries, string(u))
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, fmt.Errorf("queued url %s has no entry", u)
+	}
+	return e, nil
+}
+
+func (d *Disk) put(entries, queue *bbolt.Bucket, e *entry) error {
+	value, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal entry: %w", err)
+	}
+	if err = entries.Put([]byte(e.Job.URL), value); err != nil {
+		return fmt.Errorf("failed to put entry: %w", err)
+	}
+	if err = queue.Put(d.ordering.key(e), []byte(e.Job.URL)); err != nil {
+		return fmt.Errorf("failed to put queue key: %w", err)
+	}
+	return nil
+}
+
+func (d *Disk) delete(entries, queue *bbolt.Bucket, e *entry) error {
+	if err := entries.Delete([]byte(e.Job.URL)); err != nil {
+		return fmt.Errorf("failed to delete entry: %w", err)
+	}
+	if err := queue.Delete(d.ordering.key(e)); err != nil {
+		return fmt.Errorf("failed to delete queue key: %w", err)
+	}
+	return nil
+}
+
+// getEntry returns the entry of the url, it returns nil if the url is not queued.
+func getEntry(entries *bbolt.Bucket, u string) (*entry, error) {
+	value := entries.Get([]byte(u))
+	if value == nil {
+		return nil, nil
+	}
+	var e entry
+	if err := json.Unmarshal(value, &e); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+	return &e, nil
+}
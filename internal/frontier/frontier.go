@@ -0,0 +1,139 @@
+package frontier
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/triabokon/goscout/internal/crawler"
+)
+
+// Frontier is a crawler frontier that should be closed once the crawl is finished.
+type Frontier interface {
+	crawler.Frontier
+	io.Closer
+}
+
+// New creates the frontier of the configured storage.
+func New(c Config) (Frontier, error) {
+	switch c.Storage {
+	case StorageMemory:
+		return NewMemory(c)
+	case StorageDisk:
+		return NewDisk(c)
+	default:
+		return nil, fmt.Errorf("unknown frontier storage %q", c.Storage)
+	}
+}
+
+// pattern gives the priority to urls matching its regexp.
+type pattern struct {
+	re       *regexp.Regexp
+	priority int
+}
+
+// ordering contains the order of jobs and priorities of url patterns, it is shared by frontier storages.
+type ordering struct {
+	order    string
+	patterns []pattern
+}
+
+func newOrdering(c Config) (*ordering, error) {
+	switch c.Order {
+	case OrderBFS, OrderInlinks, OrderPattern:
+	default:
+		return nil, fmt.Errorf("unknown frontier order %q", c.Order)
+	}
+	o := &ordering{order: c.Order}
+	for _, p := range c.Patterns {
+		parsed, err := parsePattern(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse pattern %q: %w", p, err)
+		}
+		o.patterns = append(o.patterns, parsed)
+	}
+	return o, nil
+}
+
+// priority returns the priority of the first pattern matching the url.
+func (o *ordering) priority(u string) int {
+	for _, p := range o.patterns {
+		if p.re.MatchString(u) {
+			return p.priority
+		}
+	}
+	return 0
+}
+
+// before checks if the first entry is crawled before the second one.
+func (o *ordering) before(a, b *entry) bool {
+	switch o.order {
+	case OrderInlinks:
+		if a.Inlinks != b.Inlinks {
+			return a.Inlinks > b.Inlinks
+		}
+	case OrderPattern:
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+	}
+	// shallower jobs are crawled first, then jobs that were queued earlier
+	if a.Job.Depth != b.Job.Depth {
+		return a.Job.Depth < b.Job.Depth
+	}
+	return a.Seq < b.Seq
+}
+
+// key encodes the entry, so keys of entries that are crawled first are sorted first, the same way as before.
+func (o *ordering) key(e *entry) []byte {
+	key := make([]byte, 0, 16)
+	switch o.order {
+	case OrderInlinks:
+		// more inlinks go first, so they are inverted
+		key = binary.BigEndian.AppendUint32(key, ^uint32(e.Inlinks))
+	case OrderPattern:
+		// the sign bit is flipped to sort signed priorities as unsigned, then higher priorities are inverted to go first
+		key = binary.BigEndian.AppendUint32(key, ^(uint32(int32(e.Priority)) ^ 1<<31))
+	}
+	key = binary.BigEndian.AppendUint32(key, uint32(e.Job.Depth))
+	return binary.BigEndian.AppendUint64(key, e.Seq)
+}
+
+// entry is a queued job with its ordering keys.
+type entry struct {
+	Job crawler.Job
+	// Inlinks is the number of times the url was pushed, so the number of pages linking to it
+	Inlinks  int
+	Priority int
+	// Seq is the order the job was pushed in, so jobs of the same priority are crawled first in first out
+	Seq uint64
+}
+
+// update counts the new inlink of the queued url, keeping the shortest path to it.
+func (e *entry) update(j crawler.Job) {
+	e.Inlinks++
+	if j.Depth < e.Job.Depth {
+		e.Job = j
+	}
+}
+
+// parsePattern parses "<regexp> <priority>" pair, the regexp could contain spaces.
+func parsePattern(p string) (pattern, error) {
+	p = strings.TrimSpace(p)
+	i := strings.LastIndexByte(p, ' ')
+	if i < 0 {
+		return pattern{}, fmt.Errorf("pattern should have regexp and priority")
+	}
+	priority, err := strconv.Atoi(p[i+1:])
+	if err != nil {
+		return pattern{}, fmt.Errorf("failed to parse priority: %w", err)
+	}
+	re, err := regexp.Compile(strings.TrimSpace(p[:i]))
+	if err != nil {
+		return pattern{}, fmt.Errorf("failed to compile regexp: %w", err)
+	}
+	return pattern{re: re, priority: priority}, nil
+}
@@ -0,0 +1,161 @@
+package frontier_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triabokon/goscout/internal/crawler"
+	"github.com/triabokon/goscout/internal/frontier"
+)
+
+// storages runs the test against frontiers of every storage created with the config.
+func storages(t *testing.T, c frontier.Config, test func(t *testing.T, f frontier.Frontier)) {
+	for _, storage := range []string{frontier.StorageMemory, frontier.StorageDisk} {
+		t.Run(storage, func(t *testing.T) {
+			c.Storage, c.Dir = storage, t.TempDir()
+			f, err := frontier.New(c)
+			require.NoError(t, err)
+			defer func() { assert.NoError(t, f.Close()) }()
+			test(t, f)
+		})
+	}
+}
+
+// push pushes the job, failing the test if it is dropped.
+func push(t *testing.T, f frontier.Frontier, j crawler.Job) {
+	_, dropped, err := f.Push(j)
+	require.NoError(t, err)
+	assert.False(t, dropped)
+}
+
+// popAll pops urls of all queued jobs in order.
+func popAll(t *testing.T, f frontier.Frontier) []string {
+	var urls []string
+	for {
+		j, ok, err := f.Pop()
+		require.NoError(t, err)
+		if !ok {
+			return urls
+		}
+		urls = append(urls, j.URL)
+	}
+}
+
+func TestFrontier_Order(t *testing.T) {
+	jobs := []crawler.Job{
+		{URL: "https://example.com/a/1", Depth: 3},
+		{URL: "https://example.com/blog", Depth: 2},
+		{URL: "https://example.com/a", Depth: 2},
+		{URL: "https://example.com/b", Depth: 2},
+		{URL: "https://example.com/a/1", Depth: 3},
+		{URL: "https://example.com/b", Depth: 2},
+		{URL: "https://example.com/b", Depth: 2},
+	}
+	for name, tc := range map[string]struct {
+		config   frontier.Config
+		expected []string
+	}{
+		"bfs": {
+			config: frontier.Config{Order: frontier.OrderBFS},
+			expected: []string{
+				"https://example.com/blog", "https://example.com/a", "https://example.com/b", "https://example.com/a/1",
+			},
+		},
+		"inlinks": {
+			config: frontier.Config{Order: frontier.OrderInlinks},
+			expected: []string{
+				"https://example.com/b", "https://example.com/a/1", "https://example.com/blog", "https://example.com/a",
+			},
+		},
+		"pattern": {
+			config: frontier.Config{Order: frontier.OrderPattern, Patterns: []string{`/a/ 10`, ` /blog$  -1 `}},
+			expected: []string{
+				"https://example.com/a/1", "https://example.com/a", "https://example.com/b", "https://example.com/blog",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			storages(t, tc.config, func(t *testing.T, f frontier.Frontier) {
+				for _, j := range jobs {
+					push(t, f, j)
+				}
+				assert.Equal(t, tc.expected, popAll(t, f))
+			})
+		})
+	}
+}
+
+func TestFrontier_ShortestPath(t *testing.T) {
+	storages(t, frontier.Config{Order: frontier.OrderBFS}, func(t *testing.T, f frontier.Frontier) {
+		push(t, f, crawler.Job{URL: "https://example.com/c", Depth: 4, Parent: "https://example.com/b/1"})
+		push(t, f, crawler.Job{URL: "https://example.com/b", Depth: 3, Parent: "https://example.com/a"})
+		push(t, f, crawler.Job{URL: "https://example.com/c", Depth: 2, Parent: "https://example.com"})
+		push(t, f, crawler.Job{URL: "https://example.com/c", Depth: 5, Parent: "https://example.com/b/2"})
+
+		jobs, err := f.Jobs()
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []crawler.Job{
+			{URL: "https://example.com/c", Depth: 2, Parent: "https://example.com"},
+			{URL: "https://example.com/b", Depth: 3, Parent: "https://example.com/a"},
+		}, jobs)
+		j, ok, err := f.Pop()
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, crawler.Job{URL: "https://example.com/c", Depth: 2, Parent: "https://example.com"}, j)
+	})
+}
+
+func TestFrontier_MaxSize(t *testing.T) {
+	storages(t, frontier.Config{Order: frontier.OrderBFS, MaxSize: 2}, func(t *testing.T, f frontier.Frontier) {
+		push(t, f, crawler.Job{URL: "https://example.com/a", Depth: 2})
+		push(t, f, crawler.Job{URL: "https://example.com/a/1", Depth: 3})
+
+		// the deeper job is dropped, even if it is pushed
+		dropped, ok, err := f.Push(crawler.Job{URL: "https://example.com/a/2", Depth: 3})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "https://example.com/a/2", dropped.URL)
+
+		// the shallower job replaces the deepest one
+		dropped, ok, err = f.Push(crawler.Job{URL: "https://example.com/b", Depth: 2})
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "https://example.com/a/1", dropped.URL)
+
+		// the queued url is updated without dropping jobs
+		push(t, f, crawler.Job{URL: "https://example.com/b", Depth: 3})
+		assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, popAll(t, f))
+	})
+}
+
+func TestFrontier_New(t *testing.T) {
+	for name, config := range map[string]frontier.Config{
+		"unknown storage":          {Storage: "redis", Order: frontier.OrderBFS},
+		"unknown order":            {Storage: frontier.StorageMemory, Order: "dfs"},
+		"pattern without priority": {Storage: frontier.StorageMemory, Order: frontier.OrderPattern, Patterns: []string{`/blog`}},
+		"invalid priority":         {Storage: frontier.StorageDisk, Order: frontier.OrderPattern, Patterns: []string{`/blog high`}},
+		"invalid regexp":           {Storage: frontier.StorageDisk, Order: frontier.OrderPattern, Patterns: []string{`(blog 1`}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config.Dir = t.TempDir()
+			_, err := frontier.New(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDisk_Close(t *testing.T) {
+	dir := t.TempDir()
+	f, err := frontier.NewDisk(frontier.Config{Dir: dir, Order: frontier.OrderBFS})
+	require.NoError(t, err)
+	push(t, f, crawler.Job{URL: "https://example.com", Depth: 1})
+	assert.NoError(t, f.Close())
+
+	// the database is temporary, so it is removed
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
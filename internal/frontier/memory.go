@@ -0,0 +1,139 @@
+package frontier
+
+import (
+	"container/heap"
+
+	"github.com/triabokon/goscout/internal/crawler"
+)
+
+// item is a queued entry with its positions in heaps.
+type item struct {
+	entry
+	// index is the position of the item in the best and worst heaps
+	index [2]int
+}
+
+// Memory keeps jobs waiting to be crawled in memory and returns them in the configured order.
+// Each url is queued once with the depth of the shortest path found to it.
+// It is not safe for concurrent use, the crawler guards it.
+type Memory struct {
+	config   Config
+	ordering *ordering
+
+	items map[string]*item
+	// best is popped to crawl the next job, worst is popped to drop a job when the frontier is full
+	best  *queue
+	worst *queue
+	seq   uint64
+}
+
+func NewMemory(c Config) (*Memory, error) {
+	o, err := newOrdering(c)
+	if err != nil {
+		return nil, err
+	}
+	return &Memory{
+		config:   c,
+		ordering: o,
+		items:    make(map[string]*item),
+		best:     &queue{before: func(a, b *item) bool { return o.before(&a.entry, &b.entry) }},
+		worst:    &queue{before: func(a, b *item) bool { return o.before(&b.entry, &a.entry) }, slot: 1},
+	}, nil
+}
+
+// Push queues the job, if its url is already queued, the shorter path to it is kept.
+// If the frontier is full, the job of the lowest priority is dropped and returned, it could be the pushed one.
+func (m *Memory) Push(j crawler.Job) (crawler.Job, bool, error) {
+	if it, ok := m.items[j.URL]; ok {
+		it.update(j)
+		m.fix(it)
+		return crawler.Job{}, false, nil
+	}
+	it := &item{entry: entry{Job: j, Inlinks: 1, Priority: m.ordering.priority(j.URL), Seq: m.seq}}
+	m.seq++
+	if m.config.MaxSize <= 0 || len(m.items) < m.config.MaxSize {
+		m.add(it)
+		return crawler.Job{}, false, nil
+	}
+	worst := m.worst.items[0]
+	if !m.ordering.before(&it.entry, &worst.entry) {
+		return j, true, nil
+	}
+	m.remove(worst)
+	m.add(it)
+	return worst.Job, true, nil
+}
+
+// Pop returns the next job to crawl, it returns false if the frontier is empty.
+func (m *Memory) Pop() (crawler.Job, bool, error) {
+	if len(m.items) == 0 {
+		return crawler.Job{}, false, nil
+	}
+	it := m.best.items[0]
+	m.remove(it)
+	return it.Job, true, nil
+}
+
+// Jobs returns all queued jobs in no particular order.
+func (m *Memory) Jobs() ([]crawler.Job, error) {
+	jobs := make([]crawler.Job, 0, len(m.items))
+	for _, it := range m.items {
+		jobs = append(jobs, it.Job)
+	}
+	return jobs, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (m *Memory) add(it *item) {
+	m.items[it.Job.URL] = it
+	heap.Push(m.best, it)
+	heap.Push(m.worst, it)
+}
+
+func (m *Memory) remove(it *item) {
+	delete(m.items, it.Job.URL)
+	heap.Remove(m.best, it.index[m.best.slot])
+	heap.Remove(m.worst, it.index[m.worst.slot])
+}
+
+func (m *Memory) fix(it *item) {
+	heap.Fix(m.best, it.index[m.best.slot])
+	heap.Fix(m.worst, it.index[m.worst.slot])
+}
+
+// queue is a heap of items, each item is kept in two queues at once, so it stores its index in the slot of the queue.
+type queue struct {
+	items  []*item
+	before func(a, b *item) bool
+	slot   int
+}
+
+func (q *queue) Len() int { return len(q.items) }
+
+func (q *queue) Less(i, j int) bool { return q.before(q.items[i], q.items[j]) }
+
+func (q *queue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index[q.slot] = i
+	q.items[j].index[q.slot] = j
+}
+
+func (q *queue) Push(x interface{}) {
+	it, ok := x.(*item)
+	if !ok {
+		return
+	}
+	it.index[q.slot] = len(q.items)
+	q.items = append(q.items, it)
+}
+
+func (q *queue) Pop() interface{} {
+	last := len(q.items) - 1
+	it := q.items[last]
+	q.items[last] = nil
+	q.items = q.items[:last]
+	return it
+}
@@ -0,0 +1,41 @@
+package normalize
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+// Trailing slash policies.
+const (
+	// TrailingSlashKeep leaves paths as they are.
+	TrailingSlashKeep = "keep"
+	// TrailingSlashAdd adds trailing slash to paths that do not look like files.
+	TrailingSlashAdd = "add"
+	// TrailingSlashRemove removes trailing slash from paths other than the root.
+	TrailingSlashRemove = "remove"
+)
+
+type Config struct {
+	StripFragment bool
+	SortQuery     bool
+	// StripParams are regexps of query parameter names that are removed, such as tracking or session ids
+	StripParams   []string
+	TrailingSlash string
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "NormalizeConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.BoolVar(&c.StripFragment, "strip_fragment", true, "remove fragments from urls")
+	f.BoolVar(&c.SortQuery, "sort_query", true, "sort query parameters of urls")
+	f.StringArrayVar(
+		&c.StripParams, "strip_param",
+		[]string{`(?i)^utm_`, `(?i)^(fbclid|gclid|msclkid|mc_cid|mc_eid)$`, `(?i)^(sid|sessionid|jsessionid|phpsessid)$`},
+		"regexp of query parameter names to remove from urls, such as tracking or session parameters",
+	)
+	f.StringVar(&c.TrailingSlash, "trailing_slash", TrailingSlashKeep, "trailing slash policy: keep, add or remove")
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
@@ -0,0 +1,298 @@
+package normalize
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// maxTrackedVariants is the number of normalized urls and collapsed variants that are tracked,
+// so counting variants does not grow with the site, the count is a lower bound once it is reached.
+const maxTrackedVariants = 1000000
+
+// defaultPort returns the port that is removed from urls of the scheme.
+func defaultPort(scheme string) string {
+	switch scheme {
+	case "http":
+		return "80"
+	case "https":
+		return "443"
+	default:
+		return ""
+	}
+}
+
+// Normalizer rewrites urls to their normalized form, so the same page is not crawled by different urls.
+// Host is lowercased, default port, dot segments and needless percent-encoding are removed,
+// while fragments, query parameters and trailing slash are handled according to the config.
+type Normalizer struct {
+	config      Config
+	stripParams []*regexp.Regexp
+
+	mu sync.Mutex
+	// firstVariants are hashes of the first urls followed by hashes of their normalized urls,
+	// other variants are collapsed into them
+	firstVariants map[uint64]uint64
+	// collapsedVariants are hashes of distinct urls that were collapsed into normalized urls followed by other urls
+	collapsedVariants map[uint64]struct{}
+}
+
+func New(c Config) (*Normalizer, error) {
+	switch c.TrailingSlash {
+	case TrailingSlashKeep, TrailingSlashAdd, TrailingSlashRemove:
+	default:
+		return nil, fmt.Errorf("unknown trailing slash policy %q", c.TrailingSlash)
+	}
+	stripParams := make([]*regexp.Regexp, 0, len(c.StripParams))
+	for _, p := range c.StripParams {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile strip param regexp: %w", err)
+		}
+		stripParams = append(stripParams, re)
+	}
+	return &Normalizer{
+		config:            c,
+		stripParams:       stripParams,
+		firstVariants:     make(map[uint64]uint64),
+		collapsedVariants: make(map[uint64]struct{}),
+	}, nil
+}
+
+// Normalize parses and normalizes the url.
+func (n *Normalizer) Normalize(u string) (string, error) {
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse url: %w", err)
+	}
+	return n.NormalizeURL(parsedURL).String(), nil
+}
+
+// NormalizeURL returns normalized copy of the url.
+func (n *Normalizer) NormalizeURL(u *url.URL) *url.URL {
+	normalized := *u
+	normalized.Scheme = strings.ToLower(u.Scheme)
+	normalized.Host = n.host(normalized.Scheme, u.Host)
+	if n.config.StripFragment {
+		normalized.Fragment, normalized.RawFragment = "", ""
+	}
+	if !u.IsAbs() || u.Opaque != "" {
+		return &normalized
+	}
+
+	p := normalizeEscapes(removeDotSegments(u.EscapedPath()))
+	if p == "" {
+		p = "/"
+	}
+	p = n.trailingSlash(p)
+	// path could not be invalid, since its escapes were normalized
+	normalized.Path, _ = url.PathUnescape(p)
+	normalized.RawPath = p
+	normalized.RawQuery = n.query(u.RawQuery)
+	normalized.ForceQuery = false
+	return &normalized
+}
+
+// AddVariant records the url that was followed by its normalized url, it is counted as collapsed
+// if another url was followed by the same normalized url before.
+func (n *Normalizer) AddVariant(raw, normalized string) {
+	rawHash, normalizedHash := hash(raw), hash(normalized)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	first, ok := n.firstVariants[normalizedHash]
+	if !ok {
+		if len(n.firstVariants) < maxTrackedVariants {
+			n.firstVariants[normalizedHash] = rawHash
+		}
+		return
+	}
+	if rawHash != first && len(n.collapsedVariants) < maxTrackedVariants {
+		n.collapsedVariants[rawHash] = struct{}{}
+	}
+}
+
+// Collapsed returns the number of distinct urls that were collapsed into normalized urls followed by other urls.
+func (n *Normalizer) Collapsed() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.collapsedVariants)
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+func (n *Normalizer) host(scheme, host string) string {
+	host = strings.ToLower(host)
+	if port := defaultPort(scheme); port != "" {
+		host = strings.TrimSuffix(host, ":"+port)
+	}
+	host = strings.TrimSuffix(host, ".")
+	// international host names are converted to punycode, so both forms of the host are the same url,
+	// the host is kept as it is if it is not a valid host name
+	if ascii, err := ASCIIHost(host); err == nil {
+		return ascii
+	}
+	return host
+}
+
+// ASCIIHost converts the international host name with optional port to punycode.
+// Hosts without non-ASCII characters, such as IP addresses, are returned as they are.
+func ASCIIHost(host string) (string, error) {
+	if IsASCII(host) {
+		return host, nil
+	}
+	name, port := host, ""
+	if i := strings.LastIndexByte(host, ':'); i >= 0 && !strings.HasSuffix(host, "]") {
+		name, port = host[:i], host[i:]
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert host to punycode: %w", err)
+	}
+	return ascii + port, nil
+}
+
+// IsASCII checks if the string has only ASCII characters.
+func IsASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Normalizer) trailingSlash(p string) string {
+	switch n.config.TrailingSlash {
+	case TrailingSlashAdd:
+		// paths with extension are files, so they are left as they are
+		if !strings.HasSuffix(p, "/") && path.Ext(p) == "" {
+			return p + "/"
+		}
+	case TrailingSlashRemove:
+		if p != "/" {
+			return strings.TrimRight(p, "/")
+		}
+	}
+	return p
+}
+
+// query removes stripped parameters from the raw query and sorts the rest if needed.
+func (n *Normalizer) query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	params := strings.Split(rawQuery, "&")
+	kept := params[:0]
+	for _, param := range params {
+		if param == "" {
+			continue
+		}
+		param = normalizeEscapes(param)
+		key, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(key); err == nil {
+			key = unescaped
+		}
+		if n.stripped(key) {
+			continue
+		}
+		kept = append(kept, param)
+	}
+	if n.config.SortQuery {
+		sort.Strings(kept)
+	}
+	return strings.Join(kept, "&")
+}
+
+func (n *Normalizer) stripped(key string) bool {
+	for _, re := range n.stripParams {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// removeDotSegments removes "." and ".." segments from the path as described in RFC 3986.
+func removeDotSegments(p string) string {
+	if !strings.Contains(p, ".") {
+		return p
+	}
+	segments := strings.Split(p, "/")
+	result := make([]string, 0, len(segments))
+	for i, s := range segments {
+		last := i == len(segments)-1
+		switch s {
+		case ".":
+			if last {
+				result = append(result, "")
+			}
+		case "..":
+			// the first empty segment is the root, so it is kept
+			if len(result) > 1 {
+				result = result[:len(result)-1]
+			}
+			if last {
+				result = append(result, "")
+			}
+		default:
+			result = append(result, s)
+		}
+	}
+	return strings.Join(result, "/")
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters and uppercases the remaining escapes.
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
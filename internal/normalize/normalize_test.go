@@ -0,0 +1,146 @@
+package normalize_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triabokon/goscout/internal/normalize"
+)
+
+func defaultConfig() normalize.Config {
+	var c normalize.Config
+	c.Flags("")
+	return c
+}
+
+func TestNormalizer_New(t *testing.T) {
+	c := defaultConfig()
+	c.TrailingSlash = "sometimes"
+	_, err := normalize.New(c)
+	assert.EqualError(t, err, `unknown trailing slash policy "sometimes"`)
+
+	c = defaultConfig()
+	c.StripParams = []string{"(utm"}
+	_, err = normalize.New(c)
+	assert.ErrorContains(t, err, "failed to compile strip param regexp")
+}
+
+func TestNormalizer_Normalize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		tune     func(c *normalize.Config)
+		url      string
+		expected string
+	}{
+		{name: "lowercase scheme and host", url: "HTTPS://Example.COM/Path", expected: "https://example.com/Path"},
+		{name: "default https port", url: "https://example.com:443/a", expected: "https://example.com/a"},
+		{name: "default http port", url: "http://example.com:80/a", expected: "http://example.com/a"},
+		{name: "custom port", url: "https://example.com:8443/a", expected: "https://example.com:8443/a"},
+		{name: "international host", url: "https://Пример.укр:8443/a", expected: "https://xn--e1afmkfd.xn--j1amh:8443/a"},
+		{name: "empty path", url: "https://example.com", expected: "https://example.com/"},
+		{name: "fragment", url: "https://example.com/a#top", expected: "https://example.com/a"},
+		{
+			name:     "fragment is kept",
+			tune:     func(c *normalize.Config) { c.StripFragment = false },
+			url:      "https://example.com/a#top",
+			expected: "https://example.com/a#top",
+		},
+		{name: "dot segments", url: "https://example.com/a/./b/../c", expected: "https://example.com/a/c"},
+		{name: "dot segments above root", url: "https://example.com/../../a", expected: "https://example.com/a"},
+		{name: "trailing dot segment", url: "https://example.com/a/b/..", expected: "https://example.com/a/"},
+		{name: "unreserved escapes", url: "https://example.com/%7Euser/%61bc", expected: "https://example.com/~user/abc"},
+		{name: "escapes are uppercased", url: "https://example.com/a%2fb%c3%a9", expected: "https://example.com/a%2Fb%C3%A9"},
+		{name: "sorted query", url: "https://example.com/a?b=2&a=1&c", expected: "https://example.com/a?a=1&b=2&c"},
+		{
+			name:     "unsorted query",
+			tune:     func(c *normalize.Config) { c.SortQuery = false },
+			url:      "https://example.com/a?b=2&a=1",
+			expected: "https://example.com/a?b=2&a=1",
+		},
+		{
+			name:     "tracking and session params",
+			url:      "https://example.com/a?utm_source=x&UTM_Medium=y&id=1&fbclid=z&PHPSESSID=s",
+			expected: "https://example.com/a?id=1",
+		},
+		{name: "only stripped params", url: "https://example.com/a?utm_source=x", expected: "https://example.com/a"},
+		{name: "empty query", url: "https://example.com/a?", expected: "https://example.com/a"},
+		{
+			name:     "add trailing slash",
+			tune:     func(c *normalize.Config) { c.TrailingSlash = normalize.TrailingSlashAdd },
+			url:      "https://example.com/a",
+			expected: "https://example.com/a/",
+		},
+		{
+			name:     "add trailing slash skips files",
+			tune:     func(c *normalize.Config) { c.TrailingSlash = normalize.TrailingSlashAdd },
+			url:      "https://example.com/a.html",
+			expected: "https://example.com/a.html",
+		},
+		{
+			name:     "remove trailing slash",
+			tune:     func(c *normalize.Config) { c.TrailingSlash = normalize.TrailingSlashRemove },
+			url:      "https://example.com/a/",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "remove trailing slash keeps root",
+			tune:     func(c *normalize.Config) { c.TrailingSlash = normalize.TrailingSlashRemove },
+			url:      "https://example.com/",
+			expected: "https://example.com/",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := defaultConfig()
+			if tc.tune != nil {
+				tc.tune(&c)
+			}
+			n, err := normalize.New(c)
+			assert.NoError(t, err)
+			normalized, err := n.Normalize(tc.url)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, normalized)
+		})
+	}
+}
+
+func TestNormalizer_Collapsed(t *testing.T) {
+	n, err := normalize.New(defaultConfig())
+	assert.NoError(t, err)
+
+	for _, u := range []string{
+		"https://example.com/a#top",
+		"https://example.com/b#top",
+		"https://example.com/a#top",
+		"https://EXAMPLE.com/a",
+		"https://example.com/a",
+		"https://example.com/a",
+		"https://example.com/a?utm_source=x",
+	} {
+		normalized, nErr := n.Normalize(u)
+		assert.NoError(t, nErr)
+		assert.Contains(t, []string{"https://example.com/a", "https://example.com/b"}, normalized)
+		n.AddVariant(u, normalized)
+	}
+	// the first variant is not collapsed and the same variant is counted once
+	assert.Equal(t, 3, n.Collapsed())
+}
+
+func TestASCIIHost(t *testing.T) {
+	for host, expected := range map[string]string{
+		"example.com":        "example.com",
+		"приклад.укр":        "xn--80aikifvh.xn--j1amh",
+		"приклад.укр:8080":   "xn--80aikifvh.xn--j1amh:8080",
+		"[2001:db8::1]":      "[2001:db8::1]",
+		"[2001:db8::1]:8080": "[2001:db8::1]:8080",
+	} {
+		ascii, err := normalize.ASCIIHost(host)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, ascii, host)
+	}
+
+	_, err := normalize.ASCIIHost("при\u200dклад.укр")
+	assert.Error(t, err)
+}
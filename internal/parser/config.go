@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+// DefaultMaxBodySize is the default limit of the page body, which is far above the size of usual html pages.
+const DefaultMaxBodySize = 10 << 20
+
+type Config struct {
+	// MaxBodySize is the maximum number of body bytes read from a page, 0 disables the limit
+	MaxBodySize int64
+	// FollowForms enables following actions of forms submitted with GET method
+	FollowForms bool
+	// FetchStylesheets enables fetching linked stylesheets to find the assets they use
+	FetchStylesheets bool
+	// DefaultCharset is the encoding of pages that do not declare one and are not valid UTF-8,
+	// if it is empty, windows-1252 is used as html standard suggests
+	DefaultCharset string
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "ParserConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.Int64Var(
+		&c.MaxBodySize, "max_body_size",
+		DefaultMaxBodySize, "maximum number of body bytes read from a page, larger pages are truncated, 0 disables the limit",
+	)
+	f.BoolVar(
+		&c.FollowForms, "follow_forms",
+		false, "follow actions of forms submitted with GET method, such as search forms",
+	)
+	f.BoolVar(
+		&c.FetchStylesheets, "fetch_stylesheets",
+		true, "fetch linked stylesheets to find images and fonts they use, each stylesheet is fetched once",
+	)
+	f.StringVar(
+		&c.DefaultCharset, "default_charset",
+		"", "encoding of pages that do not declare one and are not valid UTF-8, such as windows-1251 or shift_jis, "+
+			"windows-1252 if empty",
+	)
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
@@ -0,0 +1,190 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// stylesheet is the fetched stylesheet, it is fetched once and shared by all pages that link it.
+type stylesheet struct {
+	once sync.Once
+	// urls are asset urls used by the stylesheet, imports are urls of stylesheets it imports
+	urls    []string
+	imports []string
+}
+
+// stylesheetURLs fetches stylesheets and stylesheets they import, and returns their asset urls
+// along with urls of imported stylesheets, and the size of bodies of stylesheets fetched by this call.
+func (p *Parser) stylesheetURLs(ctx context.Context, urls []string) (found []string, size int64) {
+	visited := make(map[string]bool, len(urls))
+	for depth := 0; len(urls) > 0 && depth <= maxImportDepth; depth++ {
+		var imports []string
+		for _, u := range urls {
+			if visited[u] {
+				continue
+			}
+			visited[u] = true
+			s, fetched := p.stylesheet(ctx, u)
+			found = append(found, s.urls...)
+			imports = append(imports, s.imports...)
+			size += fetched
+		}
+		urls = imports
+	}
+	return found, size
+}
+
+// stylesheet returns the stylesheet, fetching it if it is requested first,
+// along with the size of its body if it was fetched by this call.
+func (p *Parser) stylesheet(ctx context.Context, u string) (*stylesheet, int64) {
+	value, _ := p.stylesheets.LoadOrStore(u, &stylesheet{})
+	s, _ := value.(*stylesheet)
+	var size int64
+	s.once.Do(func() {
+		s.urls, s.imports, size = p.fetchStylesheet(ctx, u)
+	})
+	return s, size
+}
+
+// fetchStylesheet fetches and parses the stylesheet, it is fetched as a page of the site,
+// so it is skipped if robots.txt disallows it and waits for the crawl delay of its host.
+// Stylesheets are assets of the page, so if one could not be fetched, it has no urls instead of failing the page.
+func (p *Parser) fetchStylesheet(ctx context.Context, u string) (urls, imports []string, size int64) {
+	if p.robots != nil {
+		if allowed, err := p.robots.Allowed(ctx, u); err != nil || !allowed {
+			return nil, nil, 0
+		}
+		if err := p.robots.Wait(ctx, u); err != nil {
+			return nil, nil, 0
+		}
+	}
+	resp, err := p.follow(ctx, u)
+	if err != nil {
+		return nil, nil, 0
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, nil, 0
+	}
+	if mt := mediaType(resp.Header.Get(HeaderContentType)); mt != "" && mt != MediaTypeCSS {
+		return nil, nil, 0
+	}
+	var body io.Reader = resp.Body
+	if p.config.MaxBodySize > 0 {
+		body = io.LimitReader(body, p.config.MaxBodySize)
+	}
+	data, err := io.ReadAll(body)
+	size = int64(len(data))
+	if err != nil {
+		return nil, nil, size
+	}
+	values, importValues := parseCSS(string(data))
+	// urls of the stylesheet are relative to it and their queries are encoded as UTF-8,
+	// invalid and out of scope urls are ignored
+	for _, v := range values {
+		if resolved, rErr := p.resolveURL(ctx, v, resp.url, resp.url, nil); rErr == nil {
+			urls = append(urls, resolved)
+		}
+	}
+	for _, v := range importValues {
+		if resolved, rErr := p.resolveURL(ctx, v, resp.url, resp.url, nil); rErr == nil {
+			imports = append(imports, resolved)
+		}
+	}
+	return urls, imports, size
+}
+
+// parseCSS returns urls of url() functions and @import rules of the stylesheet, imports are included in urls.
+func parseCSS(css string) (urls, imports []string) {
+	for i := 0; i < len(css); {
+		rest := css[i:]
+		switch {
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				return urls, imports
+			}
+			i += end + 4
+		case rest[0] == '"' || rest[0] == '\'':
+			// strings are skipped, so urls in content values are not taken
+			_, n := cssString(rest)
+			i += n
+		case hasPrefixFold(rest, "@import"):
+			arg := strings.TrimLeft(rest[len("@import"):], cssSpace)
+			skipped := len(rest) - len(arg)
+			u, n, ok := "", 0, false
+			switch {
+			case strings.HasPrefix(arg, `"`), strings.HasPrefix(arg, "'"):
+				u, n = cssString(arg)
+				ok = true
+			case hasPrefixFold(arg, "url("):
+				u, n, ok = cssFunctionURL(arg)
+			}
+			if ok && u != "" {
+				urls, imports = append(urls, u), append(imports, u)
+			}
+			i += skipped + n
+		case hasPrefixFold(rest, "url("):
+			u, n, ok := cssFunctionURL(rest)
+			if ok && u != "" {
+				urls = append(urls, u)
+			}
+			i += n
+		default:
+			i++
+		}
+	}
+	return urls, imports
+}
+
+// cssSpace are whitespace characters of css.
+const cssSpace = " \t\r\n\f"
+
+// cssFunctionURL reads url() function at the start of the string, it returns its url and length.
+// The url could be quoted, ok is false if the function is not closed or its url is invalid.
+func cssFunctionURL(s string) (u string, n int, ok bool) {
+	arg := strings.TrimLeft(s[len("url("):], cssSpace)
+	n = len(s) - len(arg)
+	if strings.HasPrefix(arg, `"`) || strings.HasPrefix(arg, "'") {
+		var quoted int
+		u, quoted = cssString(arg)
+		arg = arg[quoted:]
+		n += quoted
+	}
+	end := strings.IndexByte(arg, ')')
+	if end < 0 {
+		return "", len(s), false
+	}
+	n += end + 1
+	if u != "" {
+		return strings.TrimSpace(u), n, true
+	}
+	// unquoted url could not have spaces, quotes and parentheses
+	u = strings.TrimSpace(arg[:end])
+	if strings.ContainsAny(u, cssSpace+`"'(`) {
+		return "", n, false
+	}
+	return u, n, true
+}
+
+// cssString reads the quoted string at the start of the string, it returns its value and length.
+func cssString(s string) (string, int) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			return s[1:i], i + 1
+		}
+	}
+	return s[1:], len(s)
+}
+
+// hasPrefixFold checks if the string starts with the prefix ignoring case.
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
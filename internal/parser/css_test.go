@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triabokon/goscout/internal/parser/mocks"
+)
+
+func TestParseCSS(t *testing.T) {
+	testCases := []struct {
+		name            string
+		css             string
+		expectedURLs    []string
+		expectedImports []string
+	}{
+		{
+			name:         "url functions",
+			css:          `a { background: URL(bg.png) } b { background: url( "img/b.png" ) url('c.svg#icon') }`,
+			expectedURLs: []string{"bg.png", "img/b.png", "c.svg#icon"},
+		},
+		{
+			name:            "imports",
+			css:             `@import "base.css"; @import url(theme.css) screen; @IMPORT 'print.css' print;`,
+			expectedURLs:    []string{"base.css", "theme.css", "print.css"},
+			expectedImports: []string{"base.css", "theme.css", "print.css"},
+		},
+		{
+			name: "comments and strings are skipped",
+			css: `/* url(commented.png) */ a::before { content: "url(quoted.png) \" url(escaped.png)" }` +
+				`b { background: url(real.png) }`,
+			expectedURLs: []string{"real.png"},
+		},
+		{
+			name: "unclosed function and comment",
+			css:  `a { background: url(broken.png } /* url(unclosed.png)`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			urls, imports := parseCSS(tc.css)
+			assert.Equal(t, tc.expectedURLs, urls)
+			assert.Equal(t, tc.expectedImports, imports)
+		})
+	}
+}
+
+func TestParser_Stylesheets(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	response := func(contentType, body string) *http.Response {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{HeaderContentType: []string{contentType}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+		}
+	}
+	page := `<html><head><link rel="stylesheet" href="/css/main.css"></head><body><img src="/logo.png"></body></html>`
+	responses := map[string]func() *http.Response{
+		"https://example.com/":      func() *http.Response { return response(MediaTypeHTML, page) },
+		"https://example.com/about": func() *http.Response { return response(MediaTypeHTML, page) },
+		"https://example.com/css/main.css": func() *http.Response {
+			return response("text/css; charset=utf-8", `@import "fonts.css"; body { background: url(../img/bg.png) }`)
+		},
+		// imported stylesheets import each other, so they are fetched once
+		"https://example.com/css/fonts.css": func() *http.Response {
+			return response(MediaTypeCSS, `@import url(main.css); @font-face { src: url(/fonts/a.woff2) }`)
+		},
+	}
+	mockClient := mocks.NewMockHTTPClient(ctrl)
+	mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		resp, ok := responses[req.URL.String()]
+		assert.True(t, ok, req.URL.String())
+		return resp(), nil
+	}).Times(len(responses))
+
+	expected := []string{
+		"https://example.com/css/main.css", "https://example.com/logo.png",
+		"https://example.com/css/fonts.css", "https://example.com/img/bg.png",
+		"https://example.com/css/main.css", "https://example.com/fonts/a.woff2",
+	}
+	p := New(Config{FetchStylesheets: true}, mockClient, nil, nil, nil, nil)
+	// stylesheets are fetched once for all pages that link them, so their size is counted by the first page
+	for _, tc := range []struct {
+		url  string
+		size int64
+	}{{url: "https://example.com/", size: 122}, {url: "https://example.com/about"}} {
+		result, err := p.ExtractURLs(context.Background(), tc.url)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, result.StaticURLs)
+		assert.Equal(t, tc.size, result.StylesheetsSize)
+	}
+}
+
+func TestParser_StylesheetsRobots(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	page := `<html><head><link rel="stylesheet" href="/private/main.css"><link rel="stylesheet" href="/css/main.css">` +
+		`</head></html>`
+	mockClient := mocks.NewMockHTTPClient(ctrl)
+	mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		body := `body { background: url(bg.png) }`
+		if req.URL.Path == "/" {
+			body = page
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}).Times(2)
+
+	ctx := context.Background()
+	mockRobots := mocks.NewMockRobots(ctrl)
+	mockRobots.EXPECT().Allowed(gomock.Any(), "https://example.com/private/main.css").Return(false, nil)
+	mockRobots.EXPECT().Allowed(gomock.Any(), "https://example.com/css/main.css").Return(true, nil)
+	mockRobots.EXPECT().Wait(ctx, "https://example.com/css/main.css").Return(nil)
+
+	result, err := New(Config{FetchStylesheets: true}, mockClient, nil, nil, nil, mockRobots).
+		ExtractURLs(ctx, "https://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://example.com/private/main.css", "https://example.com/css/main.css", "https://example.com/css/bg.png",
+	}, result.StaticURLs)
+	assert.Equal(t, int64(32), result.StylesheetsSize)
+}
@@ -0,0 +1,127 @@
+package parser
+
+import (
+	"bytes"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+
+	"github.com/triabokon/goscout/internal/normalize"
+)
+
+// utf8Charset is the name of UTF-8 encoding, pages in it are parsed as they are.
+const utf8Charset = "utf-8"
+
+// utf16Charset starts names of UTF-16 encodings, urls of such pages are encoded as UTF-8.
+const utf16Charset = "utf-16"
+
+// prescanSize is the amount of the body where the encoding of the page is looked for.
+const prescanSize = 1024
+
+// pageEncoding determines the encoding of the page by its byte order mark, Content-Type header or meta element.
+// If the page declares none of them and is not valid UTF-8, it is decoded with the default charset.
+func pageEncoding(data []byte, contentType, defaultCharset string) (encoding.Encoding, string) {
+	e, name, certain := charset.DetermineEncoding(data, contentType)
+	if certain || name == utf8Charset || metaCharset(data) != "" {
+		return e, name
+	}
+	if de, dname := charset.Lookup(defaultCharset); de != nil {
+		return de, dname
+	}
+	return e, name
+}
+
+// metaCharset returns the charset declared by the meta element at the beginning of the page.
+func metaCharset(data []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if HTMLElementType(token.DataAtom.String()) != HTMLElementTypeMeta {
+				continue
+			}
+			var equiv, content string
+			for _, attr := range token.Attr {
+				switch HTMLAttributeType(attr.Key) {
+				case HTMLAttributeTypeCharset:
+					return strings.TrimSpace(attr.Val)
+				case HTMLAttributeTypeHTTPEquiv:
+					equiv = strings.ToLower(strings.TrimSpace(attr.Val))
+				case HTMLAttributeTypeContent:
+					content = attr.Val
+				}
+			}
+			if equiv != strings.ToLower(HeaderContentType) {
+				continue
+			}
+			if _, params, err := mime.ParseMediaType(content); err == nil && params["charset"] != "" {
+				return params["charset"]
+			}
+		}
+	}
+}
+
+// toURI converts the IRI to the URI the way browsers do: international host names are converted to punycode
+// and non-ASCII characters of the query are percent-encoded in the encoding of the page, which is UTF-8 if it is nil,
+// the path is always encoded as UTF-8 once the url is printed.
+func toURI(u *url.URL, e encoding.Encoding) (*url.URL, error) {
+	host, err := normalize.ASCIIHost(u.Host)
+	if err != nil {
+		return nil, ErrURLHasInvalidHost
+	}
+	converted := *u
+	converted.Host = host
+	converted.RawQuery = escapeNonASCII(encodeQuery(u.RawQuery, e))
+	return &converted, nil
+}
+
+// encodeQuery encodes non-ASCII characters of the query in the encoding, characters the encoding
+// could not represent are replaced with escaped html numeric character references, as browsers do.
+func encodeQuery(query string, e encoding.Encoding) string {
+	if e == nil || normalize.IsASCII(query) {
+		return query
+	}
+	encoder := e.NewEncoder()
+	var b strings.Builder
+	for _, r := range query {
+		if r < utf8.RuneSelf {
+			b.WriteRune(r)
+			continue
+		}
+		encoded, err := encoder.String(string(r))
+		if err != nil {
+			encoded = "%26%23" + strconv.Itoa(int(r)) + "%3B"
+		}
+		b.WriteString(encoded)
+	}
+	return b.String()
+}
+
+// escapeNonASCII percent-encodes bytes of non-ASCII characters.
+func escapeNonASCII(s string) string {
+	if normalize.IsASCII(s) {
+		return s
+	}
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	b.Grow(len(s) * 3)
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= utf8.RuneSelf {
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xF])
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
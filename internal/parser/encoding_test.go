@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+
+	"github.com/triabokon/goscout/internal/parser/mocks"
+)
+
+func TestParser_Charset(t *testing.T) {
+	const (
+		catalog = "https://example.com/%D0%BA%D0%B0%D1%82%D0%B0%D0%BB%D0%BE%D0%B3/%D1%82%D0%BE%D0%B2%D0%B0%D1%80%D0%B8"
+		cafe    = "https://example.com/caf%C3%A9"
+	)
+	encode := func(e encoding.Encoding, s string) string {
+		encoded, err := e.NewEncoder().String(s)
+		require.NoError(t, err)
+		return encoded
+	}
+
+	testCases := []struct {
+		name           string
+		contentType    string
+		body           string
+		defaultCharset string
+		expected       []string
+	}{
+		{
+			name:        "charset of the header",
+			contentType: "text/html; charset=windows-1251",
+			body:        encode(charmap.Windows1251, `<a href="/каталог/товари">Каталог</a>`),
+			expected:    []string{catalog},
+		},
+		{
+			name:        "meta charset",
+			contentType: "text/html",
+			body:        encode(japanese.ShiftJIS, `<head><meta charset="Shift_JIS"></head><a href="/商品?q=検索">商品</a>`),
+			expected:    []string{"https://example.com/%E5%95%86%E5%93%81?q=%8C%9F%8D%F5"},
+		},
+		{
+			name:        "meta http-equiv content type",
+			contentType: "text/html",
+			body: encode(charmap.ISO8859_1,
+				`<meta http-equiv="Content-Type" content="text/html; charset=iso-8859-1"><a href="/café">Café</a>`),
+			expected: []string{cafe},
+		},
+		{
+			name: "meta charset without the header",
+			body: encode(charmap.Windows1251,
+				`<html><head><meta charset="windows-1251"></head><a href="/каталог/товари">Каталог</a></html>`),
+			expected: []string{catalog},
+		},
+		{
+			name:           "byte order mark is preferred over the header",
+			contentType:    "text/html; charset=windows-1251",
+			body:           "\xEF\xBB\xBF" + `<a href="/café">Café</a>`,
+			defaultCharset: "windows-1251",
+			expected:       []string{cafe},
+		},
+		{
+			name:           "default charset",
+			contentType:    "text/html",
+			body:           encode(charmap.Windows1251, `<a href="/каталог/товари">Каталог</a>`),
+			defaultCharset: "windows-1251",
+			expected:       []string{catalog},
+		},
+		{
+			name:        "windows-1252 without default charset",
+			contentType: "text/html",
+			body:        encode(charmap.Windows1252, `<a href="/café">Café</a>`),
+			expected:    []string{cafe},
+		},
+		{
+			name:           "valid utf-8 without declaration",
+			contentType:    "text/html",
+			body:           `<a href="/café">Café</a>`,
+			defaultCharset: "windows-1251",
+			expected:       []string{cafe},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			header := http.Header{}
+			if tc.contentType != "" {
+				header.Set(HeaderContentType, tc.contentType)
+			}
+			mockClient := mocks.NewMockHTTPClient(ctrl)
+			mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+				StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(tc.body)),
+			}, nil)
+
+			page, err := New(Config{DefaultCharset: tc.defaultCharset}, mockClient, nil, nil, nil, nil).
+				ExtractURLs(context.Background(), "https://example.com/")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, page.WebURLs)
+			assert.Equal(t, MediaTypeHTML, page.ContentType)
+		})
+	}
+}
+
+func TestParser_ResolveIRI(t *testing.T) {
+	pageURL, err := url.Parse("https://пример.укр/")
+	require.NoError(t, err)
+
+	for u, expected := range map[string]string{
+		"/товар?q=шукати#розділ": "https://xn--e1afmkfd.xn--j1amh/%D1%82%D0%BE%D0%B2%D0%B0%D1%80" +
+			"?q=%D1%88%D1%83%D0%BA%D0%B0%D1%82%D0%B8#%D1%80%D0%BE%D0%B7%D0%B4%D1%96%D0%BB",
+		"https://ПРИМЕР.укр/a":             "https://xn--e1afmkfd.xn--j1amh/a",
+		"https://xn--e1afmkfd.xn--j1amh/b": "https://xn--e1afmkfd.xn--j1amh/b",
+		"/c?q=%D1%88":                      "https://xn--e1afmkfd.xn--j1amh/c?q=%D1%88",
+	} {
+		resolved, rErr := New(Config{}, nil, nil, nil, nil, nil).
+			resolveURL(context.Background(), u, pageURL, pageURL, nil)
+		assert.NoError(t, rErr, u)
+		assert.Equal(t, expected, resolved, u)
+	}
+
+	_, err = New(Config{}, nil, nil, nil, nil, nil).
+		resolveURL(context.Background(), "https://при\u200dклад.укр/", pageURL, pageURL, nil)
+	assert.ErrorIs(t, err, ErrURLHasInvalidHost)
+
+	// the query is encoded in the encoding of the page, while the path is always encoded as UTF-8,
+	// characters the encoding does not have are sent as html character references
+	resolved, err := New(Config{}, nil, nil, nil, nil, nil).
+		resolveURL(context.Background(), "/товар?q=шукати€✓", pageURL, pageURL, charmap.Windows1251)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://xn--e1afmkfd.xn--j1amh/%D1%82%D0%BE%D0%B2%D0%B0%D1%80"+
+		"?q=%F8%F3%EA%E0%F2%E8%88%26%2310003%3B", resolved)
+}
@@ -0,0 +1,61 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/triabokon/goscout/internal/parser (interfaces: Normalizer)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	url "net/url"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockNormalizer is a mock of Normalizer interface.
+type MockNormalizer struct {
+	ctrl     *gomock.Controller
+	recorder *MockNormalizerMockRecorder
+}
+
+// MockNormalizerMockRecorder is the mock recorder for MockNormalizer.
+type MockNormalizerMockRecorder struct {
+	mock *MockNormalizer
+}
+
+// NewMockNormalizer creates a new mock instance.
+func NewMockNormalizer(ctrl *gomock.Controller) *MockNormalizer {
+	mock := &MockNormalizer{ctrl: ctrl}
+	mock.recorder = &MockNormalizerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNormalizer) EXPECT() *MockNormalizerMockRecorder {
+	return m.recorder
+}
+
+// AddVariant mocks base method.
+func (m *MockNormalizer) AddVariant(arg0, arg1 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "AddVariant", arg0, arg1)
+}
+
+// AddVariant indicates an expected call of AddVariant.
+func (mr *MockNormalizerMockRecorder) AddVariant(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddVariant", reflect.TypeOf((*MockNormalizer)(nil).AddVariant), arg0, arg1)
+}
+
+// NormalizeURL mocks base method.
+func (m *MockNormalizer) NormalizeURL(arg0 *url.URL) *url.URL {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NormalizeURL", arg0)
+	ret0, _ := ret[0].(*url.URL)
+	return ret0
+}
+
+// NormalizeURL indicates an expected call of NormalizeURL.
+func (mr *MockNormalizerMockRecorder) NormalizeURL(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NormalizeURL", reflect.TypeOf((*MockNormalizer)(nil).NormalizeURL), arg0)
+}
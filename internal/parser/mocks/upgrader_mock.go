@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/triabokon/goscout/internal/parser (interfaces: Upgrader)
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	url "net/url"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockUpgrader is a mock of Upgrader interface.
+type MockUpgrader struct {
+	ctrl     *gomock.Controller
+	recorder *MockUpgraderMockRecorder
+}
+
+// MockUpgraderMockRecorder is the mock recorder for MockUpgrader.
+type MockUpgraderMockRecorder struct {
+	mock *MockUpgrader
+}
+
+// NewMockUpgrader creates a new mock instance.
+func NewMockUpgrader(ctrl *gomock.Controller) *MockUpgrader {
+	mock := &MockUpgrader{ctrl: ctrl}
+	mock.recorder = &MockUpgraderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUpgrader) EXPECT() *MockUpgraderMockRecorder {
+	return m.recorder
+}
+
+// Upgrade mocks base method.
+func (m *MockUpgrader) Upgrade(arg0 context.Context, arg1 *url.URL) *url.URL {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Upgrade", arg0, arg1)
+	ret0, _ := ret[0].(*url.URL)
+	return ret0
+}
+
+// Upgrade indicates an expected call of Upgrade.
+func (mr *MockUpgraderMockRecorder) Upgrade(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Upgrade", reflect.TypeOf((*MockUpgrader)(nil).Upgrade), arg0, arg1)
+}
@@ -1,13 +1,130 @@
 package parser
 
-import "fmt"
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
 
 var (
 	ErrURLHasDifferentHost = fmt.Errorf("url has different host")
 	ErrURLHasInvalidSchema = fmt.Errorf("url has invalid schema")
+	ErrURLOutOfScope       = fmt.Errorf("url is out of the crawl scope")
+	ErrURLHasInvalidHost   = fmt.Errorf("url has invalid international host name")
+
+	ErrRedirectOutOfScope = fmt.Errorf("redirect leads out of the crawled site")
+	ErrRedirectLoop       = fmt.Errorf("redirect loop")
+	ErrTooManyRedirects   = fmt.Errorf("too many redirects")
+	ErrRedirectNoLocation = fmt.Errorf("redirect has no location")
+)
+
+const (
+	HTTPSSchema = "https"
+	HTTPSchema  = "http"
 )
 
-const HTTPSSchema = "https"
+const (
+	HeaderLastModified = "Last-Modified"
+	HeaderContentType  = "Content-Type"
+	HeaderLocation     = "Location"
+	HeaderXRobotsTag   = "X-Robots-Tag"
+)
+
+// MaxRedirects is the maximum number of redirects followed to fetch a web page.
+const MaxRedirects = 10
+
+// Redirect is a single hop of the redirect chain.
+type Redirect struct {
+	URL    string
+	Status int
+	// Location is the absolute url the request is redirected to
+	Location string
+}
+
+// RedirectError is returned when the redirect chain could not be followed to the end.
+type RedirectError struct {
+	Redirects []Redirect
+	Err       error
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("failed to follow redirect after %d hops: %s", len(e.Redirects), e.Err)
+}
+
+func (e *RedirectError) Unwrap() error {
+	return e.Err
+}
+
+// Media types of web pages that are parsed for urls.
+const (
+	MediaTypeHTML  = "text/html"
+	MediaTypeXHTML = "application/xhtml+xml"
+)
+
+// MediaTypeCSS is the media type of linked stylesheets that are parsed for asset urls.
+const MediaTypeCSS = "text/css"
+
+// maxImportDepth is the maximum depth of stylesheets imported by linked stylesheets that are fetched.
+const maxImportDepth = 5
+
+// sniffSize is the amount of the body used to detect its content type when the header is missing.
+const sniffSize = 512
+
+// StatusError is returned when the web page is fetched with non-2xx status code.
+// If the page was redirected, the error has the redirect chain and the url the chain ended with.
+type StatusError struct {
+	URL        string
+	StatusCode int
+	FinalURL   string
+	Redirects  []Redirect
+}
+
+func (e *StatusError) Error() string {
+	if len(e.Redirects) != 0 {
+		return fmt.Sprintf(
+			"unexpected status %d %s for %s redirected from %s",
+			e.StatusCode, http.StatusText(e.StatusCode), e.FinalURL, e.URL,
+		)
+	}
+	return fmt.Sprintf("unexpected status %d %s for %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+}
+
+// dateLayout is the date-only format of ISO 8601.
+const dateLayout = "2006-01-02"
+
+// Page is a parsed web page.
+type Page struct {
+	WebURLs    []string
+	StaticURLs []string
+	StatusCode int
+	// FinalURL is the url of the page after redirects
+	FinalURL  string
+	Redirects []Redirect
+	// ContentType is the media type of the page, only html pages have urls
+	ContentType string
+	// Size is the number of body bytes read, a page other than html is read only to detect its content type
+	Size int64
+	// Latency is the time until the response headers were received
+	Latency time.Duration
+	// Truncated is set if the page body is larger than the max body size, so only its beginning was parsed
+	Truncated bool
+	// StylesheetsSize is the size in bytes of stylesheet bodies fetched for the page,
+	// stylesheets are fetched once, so the size is counted by the first page that links them
+	StylesheetsSize int64
+	// LastModified is the page modification time from its metadata or headers, zero if unknown
+	LastModified time.Time
+	// Canonical is the absolute canonical url of the page from its link element, empty if not set
+	Canonical string
+	// NoIndex is set if the page asks not to be indexed by the meta robots element or X-Robots-Tag header
+	NoIndex bool
+	// NoFollow is set if the page asks not to follow any of its links
+	NoFollow bool
+	// NoFollowURLs are web urls of links marked with rel="nofollow", they are not included in WebURLs
+	NoFollowURLs []string
+
+	// stylesheets are urls of linked and imported stylesheets, their asset urls are added to static urls
+	stylesheets []string
+}
 
 type HTMLElementType string
 
@@ -15,6 +132,8 @@ const (
 	HTMLElementTypeA    HTMLElementType = "a"
 	HTMLElementTypeLink HTMLElementType = "link"
 	HTMLElementTypeBase HTMLElementType = "base"
+	HTMLElementTypeArea HTMLElementType = "area"
+	HTMLElementTypeForm HTMLElementType = "form"
 
 	HTMLElementTypeIFrame HTMLElementType = "iframe"
 	HTMLElementTypeEmbed  HTMLElementType = "embed"
@@ -22,6 +141,13 @@ const (
 	HTMLElementTypeImage  HTMLElementType = "image"
 	HTMLElementTypeScript HTMLElementType = "script"
 	HTMLElementTypeSource HTMLElementType = "source"
+	HTMLElementTypeVideo  HTMLElementType = "video"
+	HTMLElementTypeAudio  HTMLElementType = "audio"
+	HTMLElementTypeTrack  HTMLElementType = "track"
+	HTMLElementTypeObject HTMLElementType = "object"
+
+	HTMLElementTypeMeta  HTMLElementType = "meta"
+	HTMLElementTypeStyle HTMLElementType = "style"
 )
 
 type HTMLAttributeType string
@@ -29,4 +155,49 @@ type HTMLAttributeType string
 const (
 	HTMLAttributeTypeHref HTMLAttributeType = "href"
 	HTMLAttributeTypeSrc  HTMLAttributeType = "src"
+	HTMLAttributeTypeRel  HTMLAttributeType = "rel"
+
+	HTMLAttributeTypeSrcset      HTMLAttributeType = "srcset"
+	HTMLAttributeTypeImageSrcset HTMLAttributeType = "imagesrcset"
+	HTMLAttributeTypePoster      HTMLAttributeType = "poster"
+	HTMLAttributeTypeData        HTMLAttributeType = "data"
+	HTMLAttributeTypeStyle       HTMLAttributeType = "style"
+	HTMLAttributeTypeAction      HTMLAttributeType = "action"
+	HTMLAttributeTypeMethod      HTMLAttributeType = "method"
+	// lazy loading scripts keep image urls in data attributes until the image is shown
+	HTMLAttributeTypeDataSrc    HTMLAttributeType = "data-src"
+	HTMLAttributeTypeDataSrcset HTMLAttributeType = "data-srcset"
+
+	HTMLAttributeTypeHTTPEquiv HTMLAttributeType = "http-equiv"
+	HTMLAttributeTypeProperty  HTMLAttributeType = "property"
+	HTMLAttributeTypeName      HTMLAttributeType = "name"
+	HTMLAttributeTypeContent   HTMLAttributeType = "content"
+	HTMLAttributeTypeCharset   HTMLAttributeType = "charset"
+)
+
+// Meta element names that contain page modification time.
+const (
+	MetaLastModified        = "last-modified"
+	MetaArticleModifiedTime = "article:modified_time"
+	MetaOGUpdatedTime       = "og:updated_time"
+)
+
+// MetaRobots is the meta element name that contains robots directives for all crawlers.
+const MetaRobots = "robots"
+
+// MetaRefresh is the http-equiv value of the meta element that redirects to the url of its content.
+const MetaRefresh = "refresh"
+
+// Link relations that affect crawling.
+const (
+	RelCanonical  = "canonical"
+	RelNoFollow   = "nofollow"
+	RelStylesheet = "stylesheet"
+)
+
+// Robots directives of the meta robots element and X-Robots-Tag header.
+const (
+	RobotsNoIndex  = "noindex"
+	RobotsNoFollow = "nofollow"
+	RobotsNone     = "none"
 )
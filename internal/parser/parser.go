@@ -1,108 +1,600 @@
 package parser
 
 import (
-	"bytes"
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/net/html"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+
+	"github.com/triabokon/goscout/internal/normalize"
 )
 
 //go:generate mockgen -destination=./mocks/http_mock.go -package=mocks github.com/triabokon/goscout/internal/parser HTTPClient
 type HTTPClient interface {
-	Get(url string) (resp *http.Response, err error)
+	Do(req *http.Request) (*http.Response, error)
+}
+
+//go:generate mockgen -destination=./mocks/normalizer_mock.go -package=mocks github.com/triabokon/goscout/internal/parser Normalizer
+type Normalizer interface {
+	NormalizeURL(u *url.URL) *url.URL
+	// AddVariant records the url that was followed by its normalized url
+	AddVariant(raw, normalized string)
+}
+
+//go:generate mockgen -destination=./mocks/scope_mock.go -package=mocks github.com/triabokon/goscout/internal/parser Scope
+type Scope interface {
+	InScope(u *url.URL) bool
+}
+
+//go:generate mockgen -destination=./mocks/upgrader_mock.go -package=mocks github.com/triabokon/goscout/internal/parser Upgrader
+type Upgrader interface {
+	Upgrade(ctx context.Context, u *url.URL) *url.URL
+}
+
+//go:generate mockgen -destination=./mocks/robots_mock.go -package=mocks github.com/triabokon/goscout/internal/parser Robots
+type Robots interface {
+	Allowed(ctx context.Context, u string) (bool, error)
+	Wait(ctx context.Context, u string) error
 }
 
 type Parser struct {
-	client HTTPClient
+	config     Config
+	client     HTTPClient
+	normalizer Normalizer
+	scope      Scope
+	upgrader   Upgrader
+	robots     Robots
+	// stylesheets are fetched stylesheets by their urls
+	stylesheets *sync.Map
 }
 
-func New(c HTTPClient) *Parser {
-	return &Parser{client: c}
+// New creates a parser, the normalizer is optional and could be nil if urls should not be normalized,
+// the scope is optional as well, without it only urls on the host of the page are followed.
+// The upgrader is optional too, it upgrades urls of the scope to https after they are normalized.
+// The robots are optional as well, stylesheets are fetched only if robots.txt allows and after its crawl delay.
+func New(config Config, c HTTPClient, n Normalizer, s Scope, u Upgrader, r Robots) *Parser {
+	return &Parser{
+		config: config, client: c, normalizer: n, scope: s, upgrader: u, robots: r, stylesheets: &sync.Map{},
+	}
 }
 
-// ExtractURLs fetches web page by url and extracts all urls from it.
-func (p *Parser) ExtractURLs(u string) (webURLs, staticURLs []string, err error) {
-	tokenizer, err := p.getPageTokenizer(u)
+// ExtractURLs fetches web page by url and extracts all urls and metadata from it.
+// Redirects are followed within the site, so urls are resolved against the final page url.
+// Pages with non-2xx status return StatusError, pages other than html are not parsed and have no urls.
+func (p *Parser) ExtractURLs(ctx context.Context, u string) (*Page, error) {
+	tokenizer, resp, err := p.getPageTokenizer(ctx, u)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to fetch web page: %w", err)
+		return nil, fmt.Errorf("failed to fetch web page: %w", err)
 	}
-	baseURL, err := url.Parse(u)
+	page, err := p.readPage(ctx, tokenizer, resp)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse base url: %w", err)
+		return nil, err
+	}
+	// the page body is already closed, so its connection is not held while stylesheets are fetched
+	if p.config.FetchStylesheets && len(page.stylesheets) != 0 {
+		urls, size := p.stylesheetURLs(ctx, page.stylesheets)
+		page.StaticURLs = append(page.StaticURLs, urls...)
+		page.StylesheetsSize = size
 	}
-	return p.parseWebPage(tokenizer, baseURL)
+	return page, nil
+}
+
+// readPage parses the page body while it is streamed through the tokenizer and closes it,
+// so only the current token of the page is kept in memory.
+func (p *Parser) readPage(ctx context.Context, tokenizer *html.Tokenizer, resp *response) (*Page, error) {
+	defer resp.Body.Close()
+
+	page := &Page{}
+	if tokenizer != nil {
+		var err error
+		if page, err = p.parseWebPage(ctx, tokenizer, resp.url, resp.encoding); err != nil {
+			return nil, err
+		}
+	}
+	page.StatusCode = resp.StatusCode
+	page.FinalURL = p.finalURL(ctx, resp)
+	page.Redirects = resp.redirects
+	page.ContentType = mediaType(resp.Header.Get(HeaderContentType))
+	page.Size = *resp.read
+	page.Latency = resp.latency
+	page.Truncated = resp.truncated
+	// modification time from the page metadata is preferred, since the header is often set to the response time
+	if page.LastModified.IsZero() {
+		page.LastModified = parseTime(resp.Header.Get(HeaderLastModified))
+	}
+	// the header applies to any document, so pages other than html could be excluded from indexing as well
+	for _, value := range resp.Header.Values(HeaderXRobotsTag) {
+		noIndex, noFollow := robotsDirectives(value)
+		page.NoIndex = page.NoIndex || noIndex
+		page.NoFollow = page.NoFollow || noFollow
+	}
+	return page, nil
+}
+
+// finalURL returns the normalized final url of the page.
+func (p *Parser) finalURL(ctx context.Context, resp *response) string {
+	return p.upgrade(ctx, p.normalize(resp.url)).String()
+}
+
+// response is a fetched web page.
+type response struct {
+	*http.Response
+	// url is the final url of the page after redirects
+	url       *url.URL
+	redirects []Redirect
+	latency   time.Duration
+	// read is the number of body bytes read, a body other than html is read only to detect its type
+	read *int64
+	// truncated is set if the body is larger than the max body size, so it was not read to the end
+	truncated bool
+	// encoding is the encoding of queries of the page urls, it is nil for UTF-8
+	encoding encoding.Encoding
+}
+
+// countingReader counts bytes read from the reader.
+type countingReader struct {
+	io.Reader
+	read *int64
+}
+
+func (r countingReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	*r.read += int64(n)
+	return n, err
 }
 
-// getPageTokenizer fetch the web page and get tokenizer to parse it.
-func (p *Parser) getPageTokenizer(urlStr string) (*html.Tokenizer, error) {
-	resp, err := p.client.Get(urlStr)
+// limitReader reads up to the limit, marking the body as truncated if it has more bytes.
+type limitReader struct {
+	io.Reader
+	left      int64
+	truncated *bool
+	// probe is the buffer of the byte read past the limit
+	probe [1]byte
+}
+
+func (r *limitReader) Read(b []byte) (int, error) {
+	if r.left <= 0 {
+		// one more byte is read to tell if the body ends exactly at the limit
+		n, _ := r.Reader.Read(r.probe[:])
+		*r.truncated = *r.truncated || n > 0
+		return 0, io.EOF
+	}
+	if int64(len(b)) > r.left {
+		b = b[:r.left]
+	}
+	n, err := r.Reader.Read(b)
+	r.left -= int64(n)
+	return n, err
+}
+
+// getPageTokenizer fetches the web page and gets tokenizer to parse its body along with the response.
+// Tokenizer is nil if the page is not html, the caller must close the response body.
+func (p *Parser) getPageTokenizer(ctx context.Context, urlStr string) (*html.Tokenizer, *response, error) {
+	latency := &latencyTrace{}
+	ctx = httptrace.WithClientTrace(ctx, latency.trace())
+	started := time.Now()
+	resp, err := p.follow(ctx, urlStr)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get web page: %w", err)
+		return nil, nil, err
 	}
-	var body bytes.Buffer
-	if wErr := resp.Write(&body); wErr != nil {
-		return nil, fmt.Errorf("failed to write response to buffer: %w", wErr)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		resp.Body.Close()
+		return nil, nil, &StatusError{
+			URL: urlStr, StatusCode: resp.StatusCode, FinalURL: p.finalURL(ctx, resp), Redirects: resp.redirects,
+		}
+	}
+
+	resp.latency = latency.value(started)
+	resp.read = new(int64)
+	var reader io.Reader = resp.Body
+	if p.config.MaxBodySize > 0 {
+		reader = &limitReader{Reader: reader, left: p.config.MaxBodySize, truncated: &resp.truncated}
+	}
+	// bytes are counted past the limit, so the byte probing a truncated body is not counted
+	body := bufio.NewReaderSize(countingReader{Reader: reader, read: resp.read}, prescanSize)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, resp.Body}
+	// the same way as http server does, the content type is detected from the body if the header is missing
+	if resp.Header == nil {
+		resp.Header = make(http.Header)
+	}
+	if resp.Header.Get(HeaderContentType) == "" {
+		// error is ignored, since the content type is detected by the bytes that were read
+		// the detected charset is left out, so the encoding of the page is determined from its content
+		data, _ := body.Peek(sniffSize)
+		resp.Header.Set(HeaderContentType, mediaType(http.DetectContentType(data)))
+	}
+	switch mediaType(resp.Header.Get(HeaderContentType)) {
+	case MediaTypeHTML, MediaTypeXHTML:
+		// pages in other encodings are decoded to UTF-8, so their urls are not garbled
+		data, _ := body.Peek(prescanSize)
+		e, name := pageEncoding(data, resp.Header.Get(HeaderContentType), p.config.DefaultCharset)
+		if name == utf8Charset {
+			return html.NewTokenizer(body), resp, nil
+		}
+		// browsers encode queries of urls in the encoding of the page, except for UTF-16, which is not ASCII compatible
+		if !strings.HasPrefix(name, utf16Charset) {
+			resp.encoding = e
+		}
+		return html.NewTokenizer(transform.NewReader(body, e.NewDecoder())), resp, nil
+	default:
+		return nil, resp, nil
 	}
-	defer resp.Body.Close()
-	return html.NewTokenizer(&body), nil
+}
+
+// follow requests the url following redirects within the site and recording every hop.
+// Redirects leaving the site, loops and too long chains return RedirectError.
+func (p *Parser) follow(ctx context.Context, urlStr string) (*response, error) {
+	target, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %w", err)
+	}
+	visited := map[string]bool{target.String(): true}
+	var redirects []Redirect
+	for {
+		req, rErr := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), http.NoBody)
+		if rErr != nil {
+			return nil, fmt.Errorf("failed to create request: %w", rErr)
+		}
+		resp, dErr := p.client.Do(req)
+		if dErr != nil {
+			err = fmt.Errorf("failed to get web page: %w", dErr)
+			if len(redirects) != 0 {
+				err = &RedirectError{Redirects: redirects, Err: err}
+			}
+			return nil, err
+		}
+		if !isRedirect(resp.StatusCode) {
+			return &response{Response: resp, url: target, redirects: redirects}, nil
+		}
+		resp.Body.Close()
+
+		location, lErr := p.redirectLocation(ctx, resp, target)
+		redirect := Redirect{URL: target.String(), Status: resp.StatusCode}
+		if location != nil {
+			redirect.Location = location.String()
+		}
+		redirects = append(redirects, redirect)
+		switch {
+		case lErr != nil:
+			return nil, &RedirectError{Redirects: redirects, Err: lErr}
+		case visited[location.String()]:
+			return nil, &RedirectError{Redirects: redirects, Err: ErrRedirectLoop}
+		case len(redirects) >= MaxRedirects:
+			return nil, &RedirectError{Redirects: redirects, Err: ErrTooManyRedirects}
+		}
+		visited[location.String()] = true
+		target = location
+	}
+}
+
+// redirectLocation resolves location of the redirect response, checking that it stays within the site.
+// The location is returned even if it is out of scope, so it could be recorded.
+func (p *Parser) redirectLocation(ctx context.Context, resp *http.Response, target *url.URL) (*url.URL, error) {
+	value := resp.Header.Get(HeaderLocation)
+	if value == "" {
+		return nil, ErrRedirectNoLocation
+	}
+	location, err := target.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse redirect location: %w", err)
+	}
+	// location header is not encoded in the encoding of any page
+	if _, err = p.resolveURL(ctx, value, target, target, nil); err != nil {
+		return location, fmt.Errorf("%w: %s", ErrRedirectOutOfScope, err)
+	}
+	return location, nil
+}
+
+// isRedirect checks if the status code is a redirect with location to follow.
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// latencyTrace measures the time between writing the request and receiving the first response byte,
+// so time spent waiting for retries and rate limits is not counted.
+type latencyTrace struct {
+	mu        sync.Mutex
+	wrote     time.Time
+	firstByte time.Time
+}
+
+func (l *latencyTrace) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			l.mu.Lock()
+			l.wrote = time.Now()
+			l.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			l.mu.Lock()
+			l.firstByte = time.Now()
+			l.mu.Unlock()
+		},
+	}
+}
+
+// value returns the latency of the last request, or the time since started if the request was not traced.
+func (l *latencyTrace) value(started time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.wrote.IsZero() || l.firstByte.Before(l.wrote) {
+		return time.Since(started)
+	}
+	return l.firstByte.Sub(l.wrote)
+}
+
+// mediaType returns the media type of the content type without parameters.
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return mt
+}
+
+// document is the state of the parsed web page.
+type document struct {
+	page    *Page
+	pageURL *url.URL
+	// baseURL is the url relative urls are resolved against, it is set by the first base element with url
+	baseURL   *url.URL
+	baseFound bool
+	// encoding is the encoding of queries of the page urls, it is nil for UTF-8
+	encoding encoding.Encoding
 }
 
 // parseWebPage tokenizes the web page, collect and sorts the urls into web urls and static urls.
-func (p *Parser) parseWebPage(tokenizer *html.Tokenizer, baseURL *url.URL) (wu, su []string, err error) {
+// Relative urls are resolved against the page url, or against the first base element url once it is found.
+func (p *Parser) parseWebPage(
+	ctx context.Context, tokenizer *html.Tokenizer, pageURL *url.URL, e encoding.Encoding,
+) (*Page, error) {
+	doc := &document{page: &Page{}, pageURL: pageURL, baseURL: pageURL, encoding: e}
 	for {
 		tt := tokenizer.Next()
 		switch {
 		// if the token type is an ErrorToken, we've reached the end of the document
 		case tt == html.ErrorToken:
-			return wu, su, nil
+			return doc.page, nil
 		case tt == html.StartTagToken, tt == html.SelfClosingTagToken:
-			token := tokenizer.Token()
-			switch HTMLElementType(token.DataAtom.String()) {
-			// if element is a link or base element, add its urls to the web urls
-			case HTMLElementTypeA, HTMLElementTypeLink, HTMLElementTypeBase:
-				urls, tErr := p.handleToken(token, baseURL, HTMLAttributeTypeHref)
-				if tErr != nil {
-					return nil, nil, fmt.Errorf("failed to handle token: %w", tErr)
-				}
-				wu = append(wu, urls...)
-			// if element is an image, script, source, embed, or iframe, add its urls to the static urls
-			case HTMLElementTypeImg, HTMLElementTypeImage, HTMLElementTypeScript,
-				HTMLElementTypeSource, HTMLElementTypeEmbed, HTMLElementTypeIFrame:
-				urls, tErr := p.handleToken(token, baseURL, HTMLAttributeTypeSrc)
-				if tErr != nil {
-					return nil, nil, fmt.Errorf("failed to handle token: %w", tErr)
-				}
-				su = append(su, urls...)
+			if err := p.handleElement(ctx, tokenizer, doc); err != nil {
+				return nil, fmt.Errorf("failed to handle token: %w", err)
 			}
 		}
 	}
 }
 
-// handleToken processes html token and extracts urls by the specified attribute type.
-func (p *Parser) handleToken(token html.Token, baseURL *url.URL, attrType HTMLAttributeType) ([]string, error) {
-	urls := make([]string, 0, len(token.Attr))
+// handleElement collects urls and metadata of the element that starts with the current token.
+func (p *Parser) handleElement(ctx context.Context, tokenizer *html.Tokenizer, doc *document) error {
+	token := tokenizer.Token()
+	page := doc.page
+	switch element := HTMLElementType(token.DataAtom.String()); element {
+	// if element is the first base element with url, resolve the following urls against it,
+	// the base url itself is usually a directory, so it is not followed
+	case HTMLElementTypeBase:
+		if u := documentBaseURL(token, doc.pageURL, doc.encoding); !doc.baseFound && u != nil {
+			doc.baseURL, doc.baseFound = u, true
+		}
+	case HTMLElementTypeA, HTMLElementTypeLink, HTMLElementTypeArea:
+		if err := p.handleLink(ctx, token, element, doc); err != nil {
+			return err
+		}
+	// if element is an image, media, script, embedded or object element, add its urls to the static urls
+	case HTMLElementTypeImg, HTMLElementTypeImage, HTMLElementTypeScript, HTMLElementTypeSource,
+		HTMLElementTypeEmbed, HTMLElementTypeIFrame, HTMLElementTypeVideo, HTMLElementTypeAudio,
+		HTMLElementTypeTrack, HTMLElementTypeObject:
+		urls, err := p.handleToken(
+			ctx, token, doc,
+			HTMLAttributeTypeSrc, HTMLAttributeTypeSrcset, HTMLAttributeTypePoster, HTMLAttributeTypeData,
+		)
+		if err != nil {
+			return err
+		}
+		page.StaticURLs = append(page.StaticURLs, urls...)
+	// if element is a form submitted with GET method, its action is a web url, if forms are followed
+	case HTMLElementTypeForm:
+		if !p.config.FollowForms || !isGetForm(token) {
+			break
+		}
+		urls, err := p.handleToken(ctx, token, doc, HTMLAttributeTypeAction)
+		if err != nil {
+			return err
+		}
+		page.WebURLs = append(page.WebURLs, urls...)
+	case HTMLElementTypeMeta:
+		if err := p.handleMeta(ctx, token, doc); err != nil {
+			return err
+		}
+	// if element is a style element, add urls of its rules to the static urls,
+	// imported stylesheets are fetched as linked ones, a self-closing style element has no text
+	case HTMLElementTypeStyle:
+		if token.Type != html.StartTagToken || tokenizer.Next() != html.TextToken {
+			break
+		}
+		values, imports := parseCSS(string(tokenizer.Text()))
+		urls, err := p.resolveURLs(ctx, values, doc)
+		if err != nil {
+			return err
+		}
+		page.StaticURLs = append(page.StaticURLs, urls...)
+		if urls, err = p.resolveURLs(ctx, imports, doc); err != nil {
+			return err
+		}
+		page.stylesheets = append(page.stylesheets, urls...)
+	}
+	// images of any element could be lazy loaded or set by its inline style
+	urls, err := p.handleToken(
+		ctx, token, doc, HTMLAttributeTypeDataSrc, HTMLAttributeTypeDataSrcset, HTMLAttributeTypeStyle,
+	)
+	if err != nil {
+		return err
+	}
+	page.StaticURLs = append(page.StaticURLs, urls...)
+	return nil
+}
+
+// handleLink adds urls of the link element to the web urls, links marked with nofollow are kept apart,
+// so they are not followed, and stylesheets and preloaded images are assets of the page.
+func (p *Parser) handleLink(ctx context.Context, token html.Token, element HTMLElementType, doc *document) error {
+	page := doc.page
+	urls, err := p.handleToken(ctx, token, doc, HTMLAttributeTypeHref)
+	if err != nil {
+		return err
+	}
+	switch {
+	case hasRel(token, RelNoFollow):
+		page.NoFollowURLs = append(page.NoFollowURLs, urls...)
+	case element == HTMLElementTypeLink && hasRel(token, RelStylesheet):
+		page.StaticURLs = append(page.StaticURLs, urls...)
+		page.stylesheets = append(page.stylesheets, urls...)
+	default:
+		page.WebURLs = append(page.WebURLs, urls...)
+	}
+	if element != HTMLElementTypeLink {
+		return nil
+	}
+	if hasRel(token, RelCanonical) {
+		page.Canonical = p.canonicalURL(ctx, token, doc)
+	}
+	urls, err = p.handleToken(ctx, token, doc, HTMLAttributeTypeImageSrcset)
+	if err != nil {
+		return err
+	}
+	page.StaticURLs = append(page.StaticURLs, urls...)
+	return nil
+}
+
+// handleMeta checks if the meta element has the page modification time, robots directives
+// or the url the page refreshes to.
+func (p *Parser) handleMeta(ctx context.Context, token html.Token, doc *document) error {
+	page := doc.page
+	if t := metaLastModified(token); !t.IsZero() {
+		page.LastModified = t
+	}
+	noIndex, noFollow := metaRobots(token)
+	page.NoIndex = page.NoIndex || noIndex
+	page.NoFollow = page.NoFollow || noFollow
+	if u, ok := metaRefreshURL(token); ok {
+		urls, err := p.resolveURLs(ctx, []string{u}, doc)
+		if err != nil {
+			return err
+		}
+		page.WebURLs = append(page.WebURLs, urls...)
+	}
+	return nil
+}
+
+// handleToken processes html token and extracts urls by the specified attribute types.
+// Srcset attributes have several urls and style attributes are parsed as css.
+func (p *Parser) handleToken(
+	ctx context.Context, token html.Token, doc *document, attrTypes ...HTMLAttributeType,
+) ([]string, error) {
+	var values []string
 	for _, attr := range token.Attr {
-		if HTMLAttributeType(attr.Key) == attrType {
-			u, err := p.resolveURL(attr.Val, baseURL)
-			switch err {
-			case nil:
-				urls = append(urls, u)
-			case ErrURLHasInvalidSchema, ErrURLHasDifferentHost:
-				// if url has invalid schema or different host, ignore it
+		for _, attrType := range attrTypes {
+			if HTMLAttributeType(attr.Key) != attrType {
+				continue
+			}
+			switch attrType {
+			case HTMLAttributeTypeSrcset, HTMLAttributeTypeImageSrcset, HTMLAttributeTypeDataSrcset:
+				values = append(values, srcsetURLs(attr.Val)...)
+			case HTMLAttributeTypeStyle:
+				urls, _ := parseCSS(attr.Val)
+				values = append(values, urls...)
 			default:
-				return nil, fmt.Errorf("failed to resolve url: %w", err)
+				values = append(values, attr.Val)
 			}
 		}
 	}
+	return p.resolveURLs(ctx, values, doc)
+}
+
+// resolveURLs resolves and validates urls of the page, invalid schema and out of scope urls are skipped.
+func (p *Parser) resolveURLs(ctx context.Context, values []string, doc *document) ([]string, error) {
+	urls := make([]string, 0, len(values))
+	for _, v := range values {
+		u, err := p.resolveURL(ctx, v, doc.baseURL, doc.pageURL, doc.encoding)
+		switch err {
+		case nil:
+			urls = append(urls, u)
+		case ErrURLHasInvalidSchema, ErrURLHasInvalidHost, ErrURLHasDifferentHost, ErrURLOutOfScope:
+			// if url has invalid schema or host, or is out of the crawl scope, ignore it
+		default:
+			return nil, fmt.Errorf("failed to resolve url: %w", err)
+		}
+	}
 	return urls, nil
 }
 
 // resolveURL parse url string, resolve it relative to a baseURL, and validate it.
-func (p *Parser) resolveURL(u string, baseURL *url.URL) (string, error) {
+// Without the scope only urls on the host of the page are valid, even if the base url is on another host.
+// Urls are upgraded once they are in scope, so hosts of other sites are never probed.
+// Only valid urls are recorded as variants of their normalized urls.
+// The query is encoded in the encoding of the page, which is UTF-8 if it is nil.
+func (p *Parser) resolveURL(
+	ctx context.Context, u string, baseURL, pageURL *url.URL, e encoding.Encoding,
+) (string, error) {
+	parsedURL, err := absoluteURL(u, baseURL, e)
+	if err != nil {
+		return "", err
+	}
+	// without the scope only https urls on the host of the page are followed,
+	// otherwise the scope decides which of http and https urls are followed
+	if parsedURL.Scheme != HTTPSSchema && (p.scope == nil || parsedURL.Scheme != HTTPSchema) {
+		return "", ErrURLHasInvalidSchema
+	}
+	raw := parsedURL.String()
+	parsedURL = p.normalize(parsedURL)
+	switch {
+	case p.scope != nil:
+		if !p.scope.InScope(parsedURL) {
+			return "", ErrURLOutOfScope
+		}
+		if p.upgrader != nil {
+			parsedURL = p.upgrader.Upgrade(ctx, parsedURL)
+		}
+	case !strings.EqualFold(parsedURL.Hostname(), pageHost(pageURL)):
+		return "", ErrURLHasDifferentHost
+	}
+	resolved := parsedURL.String()
+	if p.normalizer != nil {
+		p.normalizer.AddVariant(raw, resolved)
+	}
+	return resolved, nil
+}
+
+// pageHost returns the host name of the page in punycode, so it could be compared to resolved urls.
+func pageHost(pageURL *url.URL) string {
+	host, err := normalize.ASCIIHost(pageURL.Hostname())
+	if err != nil {
+		return pageURL.Hostname()
+	}
+	return host
+}
+
+// absoluteURL parses possibly quoted url string and resolves it relative to a baseURL,
+// the resolved url is converted from IRI to URI, so international urls are crawled by their ASCII form.
+func absoluteURL(u string, baseURL *url.URL, e encoding.Encoding) (*url.URL, error) {
 	u = strings.Trim(strings.TrimSpace(u), "\\\"")
 	unquotedURL, err := strconv.Unquote(u)
 	if err != nil {
@@ -111,14 +603,251 @@ func (p *Parser) resolveURL(u string, baseURL *url.URL) (string, error) {
 	}
 	parsedURL, err := url.Parse(unquotedURL)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse url: %w", err)
+		return nil, fmt.Errorf("failed to parse url: %w", err)
 	}
-	parsedURL = baseURL.ResolveReference(parsedURL)
-	if parsedURL.Scheme != HTTPSSchema {
-		return "", ErrURLHasInvalidSchema
+	return toURI(baseURL.ResolveReference(parsedURL), e)
+}
+
+// documentBaseURL returns the absolute url of the base element resolved against the page url,
+// nil is returned if the element has no href or its url is invalid or not a web url.
+func documentBaseURL(token html.Token, pageURL *url.URL, e encoding.Encoding) *url.URL {
+	for _, attr := range token.Attr {
+		if HTMLAttributeType(attr.Key) != HTMLAttributeTypeHref {
+			continue
+		}
+		u, err := absoluteURL(attr.Val, pageURL, e)
+		if err != nil || (u.Scheme != HTTPSchema && u.Scheme != HTTPSSchema) {
+			return nil
+		}
+		return u
 	}
-	if parsedURL.Hostname() != baseURL.Hostname() {
-		return "", ErrURLHasDifferentHost
+	return nil
+}
+
+// canonicalURL returns the normalized absolute url of the canonical link element,
+// it could point to another host, so it is not validated as a url to crawl.
+// Empty string is returned if the url is invalid.
+func (p *Parser) canonicalURL(ctx context.Context, token html.Token, doc *document) string {
+	for _, attr := range token.Attr {
+		if HTMLAttributeType(attr.Key) != HTMLAttributeTypeHref {
+			continue
+		}
+		u, err := absoluteURL(attr.Val, doc.baseURL, doc.encoding)
+		if err != nil || (u.Scheme != HTTPSchema && u.Scheme != HTTPSSchema) {
+			return ""
+		}
+		return p.upgrade(ctx, p.normalize(u)).String()
+	}
+	return ""
+}
+
+// srcsetURLs returns urls of the comma separated image candidates of srcset attribute,
+// each url is followed by optional width or density descriptors.
+func srcsetURLs(srcset string) []string {
+	var urls []string
+	for {
+		srcset = strings.TrimLeft(srcset, srcsetSeparators)
+		if srcset == "" {
+			return urls
+		}
+		end := strings.IndexAny(srcset, htmlSpace)
+		if end < 0 {
+			end = len(srcset)
+		}
+		u := srcset[:end]
+		srcset = srcset[end:]
+		// url ending with comma has no descriptors
+		if trimmed := strings.TrimRight(u, ","); trimmed != u {
+			urls = append(urls, trimmed)
+			continue
+		}
+		urls = append(urls, u)
+		srcset = srcset[descriptorsEnd(srcset):]
+	}
+}
+
+// descriptorsEnd returns the index of the comma that ends descriptors of the image candidate,
+// commas inside of parentheses are the part of descriptors.
+func descriptorsEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// htmlSpace are whitespace characters of html, srcsetSeparators separate image candidates of srcset.
+const (
+	htmlSpace        = " \t\n\r\f"
+	srcsetSeparators = htmlSpace + ","
+)
+
+// metaRefreshURL returns the url of the meta refresh element, such as "5; url=/next".
+func metaRefreshURL(token html.Token) (string, bool) {
+	var equiv, content string
+	for _, attr := range token.Attr {
+		switch HTMLAttributeType(attr.Key) {
+		case HTMLAttributeTypeHTTPEquiv:
+			equiv = strings.ToLower(strings.TrimSpace(attr.Val))
+		case HTMLAttributeTypeContent:
+			content = attr.Val
+		}
+	}
+	if equiv != MetaRefresh {
+		return "", false
+	}
+	// the delay is followed by semicolon or comma and the url, which could be prefixed with "url="
+	sep := strings.IndexAny(content, ";,")
+	if sep < 0 {
+		return "", false
+	}
+	u := strings.TrimLeft(content[sep+1:], htmlSpace)
+	if hasPrefixFold(u, "url") {
+		if rest := strings.TrimLeft(u[len("url"):], htmlSpace); strings.HasPrefix(rest, "=") {
+			u = strings.TrimLeft(rest[1:], htmlSpace)
+		}
+	}
+	if len(u) > 0 && (u[0] == '"' || u[0] == '\'') {
+		if end := strings.IndexByte(u[1:], u[0]); end >= 0 {
+			u = u[1 : end+1]
+		} else {
+			u = u[1:]
+		}
+	}
+	u = strings.TrimSpace(u)
+	return u, u != ""
+}
+
+// isGetForm checks if the form is submitted with GET method, which is the default one.
+func isGetForm(token html.Token) bool {
+	for _, attr := range token.Attr {
+		if HTMLAttributeType(attr.Key) == HTMLAttributeTypeMethod {
+			return strings.EqualFold(strings.TrimSpace(attr.Val), http.MethodGet)
+		}
+	}
+	return true
+}
+
+// hasRel checks if the element has the link relation, rel attribute is a space separated list.
+func hasRel(token html.Token, rel string) bool {
+	for _, attr := range token.Attr {
+		if HTMLAttributeType(attr.Key) != HTMLAttributeTypeRel {
+			continue
+		}
+		for _, r := range strings.Fields(attr.Val) {
+			if strings.EqualFold(r, rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Parser) normalize(u *url.URL) *url.URL {
+	if p.normalizer == nil {
+		return u
+	}
+	return p.normalizer.NormalizeURL(u)
+}
+
+// upgrade upgrades the url to https if it is in scope of the crawl, other urls are kept as they are.
+func (p *Parser) upgrade(ctx context.Context, u *url.URL) *url.URL {
+	if p.upgrader == nil || p.scope == nil || !p.scope.InScope(u) {
+		return u
+	}
+	return p.upgrader.Upgrade(ctx, u)
+}
+
+// metaLastModified returns page modification time from the meta element, if it has one.
+func metaLastModified(token html.Token) time.Time {
+	var name, content string
+	for _, attr := range token.Attr {
+		switch HTMLAttributeType(attr.Key) {
+		case HTMLAttributeTypeHTTPEquiv, HTMLAttributeTypeProperty, HTMLAttributeTypeName:
+			name = strings.ToLower(attr.Val)
+		case HTMLAttributeTypeContent:
+			content = attr.Val
+		}
+	}
+	switch name {
+	case MetaLastModified, MetaArticleModifiedTime, MetaOGUpdatedTime:
+		return parseTime(content)
+	}
+	return time.Time{}
+}
+
+// metaRobots returns robots directives of the meta element, if it is a meta robots element.
+func metaRobots(token html.Token) (noIndex, noFollow bool) {
+	var name, content string
+	for _, attr := range token.Attr {
+		switch HTMLAttributeType(attr.Key) {
+		case HTMLAttributeTypeName:
+			name = strings.ToLower(strings.TrimSpace(attr.Val))
+		case HTMLAttributeTypeContent:
+			content = attr.Val
+		}
+	}
+	if name != MetaRobots {
+		return false, false
+	}
+	return robotsDirectives(content)
+}
+
+// robotsDirectives parses comma separated robots directives.
+// Directives following a user agent name, as in "otherbot: noindex", apply to that crawler only and are ignored.
+func robotsDirectives(value string) (noIndex, noFollow bool) {
+	agent := ""
+	for _, d := range strings.Split(strings.ToLower(value), ",") {
+		d = strings.TrimSpace(d)
+		if name, rest, ok := strings.Cut(d, ":"); ok && !isValueDirective(strings.TrimSpace(name)) {
+			agent, d = strings.TrimSpace(name), strings.TrimSpace(rest)
+		}
+		if agent != "" {
+			continue
+		}
+		switch d {
+		case RobotsNoIndex:
+			noIndex = true
+		case RobotsNoFollow:
+			noFollow = true
+		case RobotsNone:
+			noIndex, noFollow = true, true
+		}
+	}
+	return noIndex, noFollow
+}
+
+// isValueDirective checks if the robots directive has a value after colon, so it is not a user agent name.
+func isValueDirective(name string) bool {
+	switch name {
+	case "unavailable_after", "max-snippet", "max-image-preview", "max-video-preview":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseTime parses time in the http or ISO 8601 formats, zero time is returned if the value is invalid.
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if t, err := http.ParseTime(value); err == nil {
+		return t
+	}
+	for _, layout := range []string{time.RFC3339, dateLayout} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
 	}
-	return parsedURL.String(), nil
+	return time.Time{}
 }
@@ -2,11 +2,14 @@ package parser
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/brianvoe/gofakeit/v6"
 	"github.com/golang/mock/gomock"
@@ -24,17 +27,18 @@ func TestParser_GetPageTokenizer(t *testing.T) {
 
 	mockClient := mocks.NewMockHTTPClient(ctrl)
 
-	mockClient.EXPECT().Get(gomock.Any()).Return(&http.Response{
+	mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
 		StatusCode: http.StatusOK,
 		Body:       io.NopCloser(strings.NewReader("<html><body>Test</body></html>")),
 	}, nil)
 
-	p := New(mockClient)
+	p := New(Config{}, mockClient, nil, nil, nil, nil)
 
-	tokenizer, err := p.getPageTokenizer(gfi.URL())
+	tokenizer, resp, err := p.getPageTokenizer(context.Background(), gfi.URL())
 	assert.NoError(t, err)
+	defer resp.Body.Close()
 
-	assert.Equal(t, html.TextToken, tokenizer.Next())
+	// only the body is tokenized, without the status line and headers
 	assert.Equal(t, html.StartTagToken, tokenizer.Next())
 
 	tokenName, _ := tokenizer.TagName()
@@ -57,22 +61,118 @@ func TestParser_ParseWebPage(t *testing.T) {
 			html:     `<html><body><img src="/img/image.jpg"></body></html>`,
 			expected: []string{"https://example.com/img/image.jpg"},
 		},
+		{
+			name: "base element",
+			html: `<html><head><base href="/app/"></head><body><a href="page">Page</a><base href="/other/">` +
+				`<a href="/about">About</a><img src="img/logo.png"></body></html>`,
+			expected: []string{"https://example.com/app/page", "https://example.com/about", "https://example.com/app/img/logo.png"},
+		},
+		{
+			name:     "base element on another host",
+			html:     `<html><head><base href="https://cdn.example.net/"></head><body><a href="page">Page</a></body></html>`,
+			expected: nil,
+		},
+		{
+			name: "base element without web url",
+			html: `<html><head><base target="_blank"><base href="javascript:void(0)"></head>` +
+				`<body><a href="page">Page</a></body></html>`,
+			expected: []string{"https://example.com/page"},
+		},
+		{
+			name:     "self-closing style element",
+			html:     `<html><head><style/>body { background: url(/bg.png) }</style></head></html>`,
+			expected: nil,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			parser := New(nil)
+			parser := New(Config{}, nil, nil, nil, nil, nil)
 			baseURL, pErr := url.Parse("https://example.com")
 			assert.NoError(t, pErr)
 			tokenizer := html.NewTokenizer(strings.NewReader(tc.html))
 
-			webUrls, staticUrls, err := parser.parseWebPage(tokenizer, baseURL)
+			page, err := parser.parseWebPage(context.Background(), tokenizer, baseURL, nil)
 			assert.NoError(t, err)
-			assert.Equal(t, tc.expected, append(webUrls, staticUrls...))
+			assert.Equal(t, tc.expected, append(page.WebURLs, page.StaticURLs...))
+		})
+	}
+}
+
+func TestParser_LinkSources(t *testing.T) {
+	pageURL, err := url.Parse("https://example.com/shop/")
+	assert.NoError(t, err)
+	const page = `<html><head>
+		<meta http-equiv="Refresh" content="5; URL='/shop/sale'">
+		<link rel="preload" as="image" imagesrcset="/hero-1x.jpg 1x, /hero-2x.jpg 2x">
+		<style>
+			@import "/css/print.css" print;
+			body { background: url( 'bg.png' ) } .icon::after { content: "url(not-an-image.png)" }
+		</style>
+	</head><body>
+		<img src="a.jpg" srcset="a-480.jpg 480w, a-800.jpg 800w,a-1200.jpg">
+		<picture><source srcset="/img/b.webp?size=1,2 1x, /img/b@2x.webp 2x" type="image/webp"></picture>
+		<img data-src="lazy.jpg" data-srcset="lazy-2x.jpg 2x" src="data:image/gif;base64,R0lGOD">
+		<map><area href="/shop/map-area" shape="rect"><area href="/shop/ad" rel="nofollow"></map>
+		<video src="/media/clip.mp4" poster="/media/poster.jpg"><track src="/media/subtitles.vtt"></video>
+		<object data="/media/plan.pdf"></object>
+		<div style="background-image: url(/img/banner.png)"></div>
+		<form action="/shop/search"><input name="q"></form>
+		<form action="/shop/cart" method="post"></form>
+	</body></html>`
+
+	expectedStaticURLs := []string{
+		"https://example.com/hero-1x.jpg", "https://example.com/hero-2x.jpg",
+		"https://example.com/css/print.css", "https://example.com/shop/bg.png",
+		"https://example.com/shop/a.jpg", "https://example.com/shop/a-480.jpg",
+		"https://example.com/shop/a-800.jpg", "https://example.com/shop/a-1200.jpg",
+		"https://example.com/img/b.webp?size=1,2", "https://example.com/img/b@2x.webp",
+		"https://example.com/shop/lazy.jpg", "https://example.com/shop/lazy-2x.jpg",
+		"https://example.com/media/clip.mp4", "https://example.com/media/poster.jpg",
+		"https://example.com/media/subtitles.vtt", "https://example.com/media/plan.pdf",
+		"https://example.com/img/banner.png",
+	}
+	for name, tc := range map[string]struct {
+		config          Config
+		expectedWebURLs []string
+	}{
+		"forms are not followed": {
+			expectedWebURLs: []string{"https://example.com/shop/sale", "https://example.com/shop/map-area"},
+		},
+		"get forms are followed": {
+			config: Config{FollowForms: true},
+			expectedWebURLs: []string{
+				"https://example.com/shop/sale", "https://example.com/shop/map-area", "https://example.com/shop/search",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, pErr := New(tc.config, nil, nil, nil, nil, nil).
+				parseWebPage(context.Background(), html.NewTokenizer(strings.NewReader(page)), pageURL, nil)
+			assert.NoError(t, pErr)
+			assert.Equal(t, tc.expectedWebURLs, p.WebURLs)
+			assert.Equal(t, expectedStaticURLs, p.StaticURLs)
+			assert.Equal(t, []string{"https://example.com/shop/ad"}, p.NoFollowURLs)
+			assert.Equal(t, []string{"https://example.com/css/print.css"}, p.stylesheets)
 		})
 	}
 }
 
+func TestSrcsetURLs(t *testing.T) {
+	for srcset, expected := range map[string][]string{
+		"":                                     nil,
+		"image.jpg":                            {"image.jpg"},
+		" small.jpg 480w ,\n large.jpg 1080w ": {"small.jpg", "large.jpg"},
+		"a.jpg, b.jpg 2x":                      {"a.jpg", "b.jpg"},
+		// commas are allowed in urls, only trailing ones end the candidate
+		"a.jpg,b.jpg 2x":                  {"a.jpg,b.jpg"},
+		"a.jpg 1x (ignored, part), b.jpg": {"a.jpg", "b.jpg"},
+		",, a.jpg 1x,,":                   {"a.jpg"},
+	} {
+		assert.Equal(t, expected, srcsetURLs(srcset), srcset)
+	}
+}
+
 func TestParser_HandleToken(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -108,10 +208,11 @@ func TestParser_HandleToken(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			parser := New(nil)
+			parser := New(Config{}, nil, nil, nil, nil, nil)
 			baseURL, pErr := url.Parse("https://example.com")
 			assert.NoError(t, pErr)
-			urls, err := parser.handleToken(tc.token, baseURL, tc.attrType)
+			doc := &document{baseURL: baseURL, pageURL: baseURL}
+			urls, err := parser.handleToken(context.Background(), tc.token, doc, tc.attrType)
 
 			assert.NoError(t, err)
 			assert.Equal(t, tc.expectUrls, urls)
@@ -158,11 +259,11 @@ func TestParser_ResolveURL(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			parser := New(nil)
+			parser := New(Config{}, nil, nil, nil, nil, nil)
 			baseURL, pErr := url.Parse("https://example.com")
 			assert.NoError(t, pErr)
 
-			resolvedURL, err := parser.resolveURL(tc.urlStr, baseURL)
+			resolvedURL, err := parser.resolveURL(context.Background(), tc.urlStr, baseURL, baseURL, nil)
 			if err != nil {
 				assert.Equal(t, err.Error(), tc.expectedErrorMsg)
 			}
@@ -171,6 +272,89 @@ func TestParser_ResolveURL(t *testing.T) {
 	}
 }
 
+func TestParser_Scope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockScope := mocks.NewMockScope(ctrl)
+	mockScope.EXPECT().InScope(gomock.Any()).DoAndReturn(func(u *url.URL) bool {
+		return u.Scheme == HTTPSSchema && strings.HasPrefix(u.Path, "/blog/")
+	}).Times(6)
+
+	baseURL, err := url.Parse("https://example.com/blog/")
+	assert.NoError(t, err)
+	tokenizer := html.NewTokenizer(strings.NewReader(`<html><body>
+		<a href="post">Post</a><a href="https://www.example.com/blog/post">Other host</a><a href="/about">About</a>
+		<img src="/blog/image.jpg"><img src="/static/image.jpg"><a href="http://example.com/blog/">Other scheme</a>
+		<a href="ftp://example.com/blog/">Invalid schema</a>
+	</body></html>`))
+
+	// scope is checked for page and static urls of both schemes, instead of the page host
+	page, err := New(Config{}, nil, nil, mockScope, nil, nil).parseWebPage(context.Background(), tokenizer, baseURL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/blog/post", "https://www.example.com/blog/post"}, page.WebURLs)
+	assert.Equal(t, []string{"https://example.com/blog/image.jpg"}, page.StaticURLs)
+}
+
+func TestParser_Upgrade(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mockScope := mocks.NewMockScope(ctrl)
+	mockScope.EXPECT().InScope(gomock.Any()).DoAndReturn(func(u *url.URL) bool {
+		return u.Hostname() == "example.com"
+	}).AnyTimes()
+	// only urls of the scope are upgraded, so hosts of other sites are not probed
+	mockUpgrader := mocks.NewMockUpgrader(ctrl)
+	mockUpgrader.EXPECT().Upgrade(ctx, gomock.Any()).DoAndReturn(func(_ context.Context, u *url.URL) *url.URL {
+		assert.Equal(t, "example.com", u.Hostname())
+		upgraded := *u
+		upgraded.Scheme = HTTPSSchema
+		return &upgraded
+	}).Times(3)
+
+	pageURL, err := url.Parse("http://example.com/")
+	assert.NoError(t, err)
+	tokenizer := html.NewTokenizer(strings.NewReader(`<html><head><link rel="canonical" href="http://example.com/a">
+		</head><body><a href="/b">Page</a><img src="http://tracker.thirdparty.net/pixel"></body></html>`))
+
+	page, err := New(Config{}, nil, nil, mockScope, mockUpgrader, nil).parseWebPage(ctx, tokenizer, pageURL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/a", page.Canonical)
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/b"}, page.WebURLs)
+	assert.Empty(t, page.StaticURLs)
+}
+
+func TestParser_Variants(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockScope := mocks.NewMockScope(ctrl)
+	mockScope.EXPECT().InScope(gomock.Any()).DoAndReturn(func(u *url.URL) bool {
+		return u.Hostname() == "example.com"
+	}).AnyTimes()
+	mockNormalizer := mocks.NewMockNormalizer(ctrl)
+	mockNormalizer.EXPECT().NormalizeURL(gomock.Any()).DoAndReturn(func(u *url.URL) *url.URL {
+		normalized := *u
+		normalized.Fragment = ""
+		return &normalized
+	}).AnyTimes()
+	// out of scope urls are not recorded, so they are never counted as collapsed
+	mockNormalizer.EXPECT().AddVariant("https://example.com/a#top", "https://example.com/a")
+	mockNormalizer.EXPECT().AddVariant("https://example.com/a", "https://example.com/a")
+
+	pageURL, err := url.Parse("https://example.com/")
+	assert.NoError(t, err)
+	tokenizer := html.NewTokenizer(strings.NewReader(
+		`<a href="/a#top">A</a><a href="/a">A</a><a href="https://other.com/b#top">B</a>`))
+
+	page, err := New(Config{}, nil, mockNormalizer, mockScope, nil, nil).parseWebPage(context.Background(), tokenizer, pageURL, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/a", "https://example.com/a"}, page.WebURLs)
+}
+
 func TestParser_ExtractURLs(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
@@ -180,6 +364,7 @@ func TestParser_ExtractURLs(t *testing.T) {
 	expectedStaticURLs := []string{"https://example.com/image1", "https://example.com/image2"}
 
 	mockResponse := &http.Response{
+		StatusCode: http.StatusOK,
 		Body: io.NopCloser(bytes.NewBufferString(`
             <html>
             <body>
@@ -192,11 +377,504 @@ func TestParser_ExtractURLs(t *testing.T) {
         `)),
 	}
 	mockClient := mocks.NewMockHTTPClient(mockCtrl)
-	mockClient.EXPECT().Get(u).Return(mockResponse, nil).Times(1)
+	mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, u, req.URL.String())
+		return mockResponse, nil
+	}).Times(1)
 
-	p := New(mockClient)
-	webURLs, staticURLs, err := p.ExtractURLs(u)
+	p := New(Config{}, mockClient, nil, nil, nil, nil)
+	page, err := p.ExtractURLs(context.Background(), u)
 	assert.Nil(t, err)
-	assert.Equal(t, expectedWebURLs, webURLs)
-	assert.Equal(t, expectedStaticURLs, staticURLs)
+	assert.Equal(t, expectedWebURLs, page.WebURLs)
+	assert.Equal(t, expectedStaticURLs, page.StaticURLs)
+	assert.True(t, page.LastModified.IsZero())
+}
+
+func TestParser_LastModified(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   string
+		html     string
+		expected time.Time
+	}{
+		{
+			name:     "header",
+			header:   "Wed, 21 Oct 2015 07:28:00 GMT",
+			html:     `<html><body></body></html>`,
+			expected: time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC),
+		},
+		{
+			name:     "meta property is preferred over header",
+			header:   "Wed, 21 Oct 2015 07:28:00 GMT",
+			html:     `<html><head><meta property="article:modified_time" content="2016-01-02T03:04:05Z"></head></html>`,
+			expected: time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		{
+			name:     "meta http-equiv date",
+			html:     `<html><head><meta http-equiv="Last-Modified" content="2017-05-06"></head></html>`,
+			expected: time.Date(2017, 5, 6, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "invalid time",
+			header: "yesterday",
+			html:   `<html><body></body></html>`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			header := http.Header{}
+			if tc.header != "" {
+				header.Set(HeaderLastModified, tc.header)
+			}
+			mockClient := mocks.NewMockHTTPClient(ctrl)
+			mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(tc.html)),
+			}, nil)
+
+			page, err := New(Config{}, mockClient, nil, nil, nil, nil).ExtractURLs(context.Background(), "https://example.com")
+			assert.NoError(t, err)
+			assert.True(t, tc.expected.Equal(page.LastModified), page.LastModified)
+		})
+	}
+}
+
+func TestParser_Directives(t *testing.T) {
+	testCases := []struct {
+		name              string
+		header            []string
+		contentType       string
+		html              string
+		expectedCanonical string
+		expectedNoIndex   bool
+		expectedNoFollow  bool
+		expectedWebURLs   []string
+		expectedNoFollows []string
+	}{
+		{
+			name: "no directives",
+			html: `<html><head><meta name="description" content="noindex"></head></html>`,
+		},
+		{
+			name: "relative canonical",
+			html: `<html><head><link rel="canonical" href="/page?b=2&a=1"></head></html>`,
+			// canonical url is normalized, so it could be compared with the page url
+			expectedCanonical: "https://example.com/page?a=1&b=2",
+			expectedWebURLs:   []string{"https://example.com/page?a=1&b=2"},
+		},
+		{
+			name:              "canonical on another host",
+			html:              `<html><head><link rel="Canonical" href="https://www.example.com/"></head></html>`,
+			expectedCanonical: "https://www.example.com/",
+		},
+		{
+			name:            "meta robots",
+			html:            `<html><head><meta name="ROBOTS" content="NoIndex, follow"></head></html>`,
+			expectedNoIndex: true,
+		},
+		{
+			name:             "meta robots none",
+			html:             `<html><head><meta name="robots" content="none"></head></html>`,
+			expectedNoIndex:  true,
+			expectedNoFollow: true,
+		},
+		{
+			name: "meta for another crawler",
+			html: `<html><head><meta name="otherbot" content="noindex, nofollow"></head></html>`,
+		},
+		{
+			name:              "nofollow links",
+			html:              `<html><body><a href="/a">A</a><a rel="external nofollow" href="/b">B</a></body></html>`,
+			expectedWebURLs:   []string{"https://example.com/a"},
+			expectedNoFollows: []string{"https://example.com/b"},
+		},
+		{
+			name:             "header",
+			header:           []string{"noindex", "unavailable_after: 25 Jun 2010 15:00:00 PST, nofollow"},
+			html:             `<html></html>`,
+			expectedNoIndex:  true,
+			expectedNoFollow: true,
+		},
+		{
+			name:   "header for another crawler",
+			header: []string{"otherbot: noindex, nofollow"},
+			html:   `<html></html>`,
+		},
+		{
+			name:            "header of a document other than html",
+			header:          []string{"noindex"},
+			contentType:     "application/pdf",
+			html:            `%PDF-1.4`,
+			expectedNoIndex: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			header := http.Header{}
+			for _, v := range tc.header {
+				header.Add(HeaderXRobotsTag, v)
+			}
+			if tc.contentType != "" {
+				header.Set(HeaderContentType, tc.contentType)
+			}
+			mockClient := mocks.NewMockHTTPClient(ctrl)
+			mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+				StatusCode: http.StatusOK,
+				Header:     header,
+				Body:       io.NopCloser(strings.NewReader(tc.html)),
+			}, nil)
+			mockNormalizer := mocks.NewMockNormalizer(ctrl)
+			mockNormalizer.EXPECT().NormalizeURL(gomock.Any()).DoAndReturn(func(u *url.URL) *url.URL {
+				u.RawQuery = u.Query().Encode()
+				return u
+			}).AnyTimes()
+			mockNormalizer.EXPECT().AddVariant(gomock.Any(), gomock.Any()).AnyTimes()
+
+			page, err := New(Config{}, mockClient, mockNormalizer, nil, nil, nil).
+				ExtractURLs(context.Background(), "https://example.com")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedCanonical, page.Canonical)
+			assert.Equal(t, tc.expectedNoIndex, page.NoIndex)
+			assert.Equal(t, tc.expectedNoFollow, page.NoFollow)
+			assert.Equal(t, tc.expectedWebURLs, page.WebURLs)
+			assert.Equal(t, tc.expectedNoFollows, page.NoFollowURLs)
+		})
+	}
+}
+
+// trackedBody is the response body that records how much of it was read and whether it was closed.
+type trackedBody struct {
+	*strings.Reader
+	closed bool
+}
+
+func (b *trackedBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestParser_CloseBody(t *testing.T) {
+	for name, tc := range map[string]struct {
+		status       int
+		contentType  string
+		body         string
+		expectedErr  bool
+		expectedRead bool
+	}{
+		"html page":         {status: http.StatusOK, contentType: MediaTypeHTML, body: `<a href="/a">A</a>`, expectedRead: true},
+		"html page error":   {status: http.StatusOK, contentType: MediaTypeHTML, body: `<a href="https://[::1">A</a>`, expectedErr: true, expectedRead: true},
+		"image is not read": {status: http.StatusOK, contentType: "image/png", body: "\x89PNG"},
+		"status error":      {status: http.StatusNotFound, contentType: MediaTypeHTML, body: "not found", expectedErr: true},
+		// redirect location is missing, so the chain ends with an error
+		"redirect": {status: http.StatusFound, body: "moved", expectedErr: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			body := &trackedBody{Reader: strings.NewReader(tc.body)}
+			header := http.Header{HeaderContentType: []string{tc.contentType}}
+			mockClient := mocks.NewMockHTTPClient(ctrl)
+			mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{StatusCode: tc.status, Header: header, Body: body}, nil)
+			_, err := New(Config{}, mockClient, nil, nil, nil, nil).ExtractURLs(context.Background(), "https://example.com")
+			assert.Equal(t, tc.expectedErr, err != nil, err)
+			assert.True(t, body.closed)
+			assert.Equal(t, tc.expectedRead, body.Len() == 0)
+		})
+	}
+}
+
+func TestParser_MaxBodySize(t *testing.T) {
+	const body = `<html><body><a href="/first">First</a><a href="/second">Second</a></body></html>`
+
+	for name, tc := range map[string]struct {
+		maxBodySize       int64
+		expectedURLs      []string
+		expectedSize      int64
+		expectedTruncated bool
+	}{
+		"no limit": {
+			expectedURLs: []string{"https://example.com/first", "https://example.com/second"},
+			expectedSize: int64(len(body)),
+		},
+		"body fits": {
+			maxBodySize:  int64(len(body)),
+			expectedURLs: []string{"https://example.com/first", "https://example.com/second"},
+			expectedSize: int64(len(body)),
+		},
+		"body is truncated": {
+			maxBodySize: 45, expectedURLs: []string{"https://example.com/first"}, expectedSize: 45, expectedTruncated: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClient := mocks.NewMockHTTPClient(ctrl)
+			mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{HeaderContentType: []string{"text/html"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil)
+
+			page, err := New(Config{MaxBodySize: tc.maxBodySize}, mockClient, nil, nil, nil, nil).
+				ExtractURLs(context.Background(), "https://example.com")
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedURLs, page.WebURLs)
+			assert.Equal(t, tc.expectedSize, page.Size)
+			assert.Equal(t, tc.expectedTruncated, page.Truncated)
+		})
+	}
+}
+
+func TestParser_Response(t *testing.T) {
+	const body = `<html><body><a href="/link">Link</a></body></html>`
+
+	testCases := []struct {
+		name                string
+		status              int
+		contentType         string
+		body                string
+		expectedStatus      int
+		expectedContentType string
+		expectedURLs        []string
+		// bodyUnread is set if the body is not read, so its size is not counted
+		bodyUnread bool
+	}{
+		{
+			name:                "html",
+			status:              http.StatusOK,
+			contentType:         "text/html; charset=utf-8",
+			body:                body,
+			expectedContentType: MediaTypeHTML,
+			expectedURLs:        []string{"https://example.com/link"},
+		},
+		{
+			name:                "xhtml",
+			status:              http.StatusOK,
+			contentType:         MediaTypeXHTML,
+			body:                body,
+			expectedContentType: MediaTypeXHTML,
+			expectedURLs:        []string{"https://example.com/link"},
+		},
+		{
+			name:                "html without content type",
+			status:              http.StatusNonAuthoritativeInfo,
+			body:                body,
+			expectedContentType: MediaTypeHTML,
+			expectedURLs:        []string{"https://example.com/link"},
+		},
+		{
+			name:                "pdf",
+			status:              http.StatusOK,
+			contentType:         "application/pdf",
+			body:                `%PDF-1.4 <a href="/link">`,
+			expectedContentType: "application/pdf",
+			bodyUnread:          true,
+		},
+		{
+			name:                "text without content type",
+			status:              http.StatusOK,
+			body:                "plain text",
+			expectedContentType: "text/plain",
+		},
+		{
+			name:           "not found",
+			status:         http.StatusNotFound,
+			contentType:    MediaTypeHTML,
+			body:           body,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "server error",
+			status:         http.StatusInternalServerError,
+			body:           body,
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			header := http.Header{}
+			if tc.contentType != "" {
+				header.Set(HeaderContentType, tc.contentType)
+			}
+			mockClient := mocks.NewMockHTTPClient(ctrl)
+			mockClient.EXPECT().Do(gomock.Any()).Return(&http.Response{
+				StatusCode:    tc.status,
+				Header:        header,
+				Body:          io.NopCloser(strings.NewReader(tc.body)),
+				ContentLength: int64(len(tc.body)),
+			}, nil)
+
+			page, err := New(Config{}, mockClient, nil, nil, nil, nil).ExtractURLs(context.Background(), "https://example.com")
+			if tc.expectedStatus != 0 {
+				var statusErr *StatusError
+				assert.ErrorAs(t, err, &statusErr)
+				assert.Equal(t, tc.expectedStatus, statusErr.StatusCode)
+				assert.Equal(t, "https://example.com", statusErr.URL)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, page.StatusCode)
+			assert.Equal(t, tc.expectedContentType, page.ContentType)
+			assert.Equal(t, tc.expectedURLs, page.WebURLs)
+			assert.Equal(t, "https://example.com", page.FinalURL)
+			expectedSize := int64(len(tc.body))
+			if tc.bodyUnread {
+				expectedSize = 0
+			}
+			assert.Equal(t, expectedSize, page.Size)
+		})
+	}
+}
+
+func TestParser_Redirects(t *testing.T) {
+	redirect := func(status int, location string) *http.Response {
+		header := http.Header{}
+		if location != "" {
+			header.Set(HeaderLocation, location)
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(""))}
+	}
+	page := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{HeaderContentType: []string{MediaTypeHTML}},
+		Body:       io.NopCloser(strings.NewReader(`<html><body><a href="link">Link</a></body></html>`)),
+	}
+
+	testCases := []struct {
+		name              string
+		responses         map[string]*http.Response
+		expectedFinalURL  string
+		expectedURLs      []string
+		expectedRedirects []Redirect
+		expectedErr       error
+	}{
+		{
+			name: "chain within site",
+			responses: map[string]*http.Response{
+				"https://example.com/old":      redirect(http.StatusMovedPermanently, "/docs/"),
+				"https://example.com/docs/":    redirect(http.StatusFound, "https://example.com/docs/v2/"),
+				"https://example.com/docs/v2/": page,
+			},
+			expectedFinalURL: "https://example.com/docs/v2/",
+			// links are resolved against the final url
+			expectedURLs: []string{"https://example.com/docs/v2/link"},
+			expectedRedirects: []Redirect{
+				{URL: "https://example.com/old", Status: http.StatusMovedPermanently, Location: "https://example.com/docs/"},
+				{URL: "https://example.com/docs/", Status: http.StatusFound, Location: "https://example.com/docs/v2/"},
+			},
+		},
+		{
+			name: "base element",
+			responses: map[string]*http.Response{
+				"https://example.com/old": redirect(http.StatusMovedPermanently, "/app/v2/index.html"),
+				"https://example.com/app/v2/index.html": {
+					StatusCode: http.StatusOK,
+					Header:     http.Header{HeaderContentType: []string{MediaTypeHTML}},
+					Body: io.NopCloser(strings.NewReader(
+						`<html><head><base href="../"></head><body><a href="link">Link</a></body></html>`,
+					)),
+				},
+			},
+			expectedFinalURL: "https://example.com/app/v2/index.html",
+			// the base url is resolved against the final url as well
+			expectedURLs: []string{"https://example.com/app/link"},
+			expectedRedirects: []Redirect{{
+				URL: "https://example.com/old", Status: http.StatusMovedPermanently,
+				Location: "https://example.com/app/v2/index.html",
+			}},
+		},
+		{
+			name: "out of scope",
+			responses: map[string]*http.Response{
+				"https://example.com/old": redirect(http.StatusMovedPermanently, "https://other.com/"),
+			},
+			expectedRedirects: []Redirect{
+				{URL: "https://example.com/old", Status: http.StatusMovedPermanently, Location: "https://other.com/"},
+			},
+			expectedErr: ErrRedirectOutOfScope,
+		},
+		{
+			name: "loop",
+			responses: map[string]*http.Response{
+				"https://example.com/old":  redirect(http.StatusFound, "/old/"),
+				"https://example.com/old/": redirect(http.StatusFound, "/old"),
+			},
+			expectedRedirects: []Redirect{
+				{URL: "https://example.com/old", Status: http.StatusFound, Location: "https://example.com/old/"},
+				{URL: "https://example.com/old/", Status: http.StatusFound, Location: "https://example.com/old"},
+			},
+			expectedErr: ErrRedirectLoop,
+		},
+		{
+			name: "chain to error page",
+			responses: map[string]*http.Response{
+				"https://example.com/old":  redirect(http.StatusMovedPermanently, "/gone"),
+				"https://example.com/gone": redirect(http.StatusNotFound, ""),
+			},
+			expectedFinalURL: "https://example.com/gone",
+			expectedRedirects: []Redirect{
+				{URL: "https://example.com/old", Status: http.StatusMovedPermanently, Location: "https://example.com/gone"},
+			},
+			expectedErr: &StatusError{},
+		},
+		{
+			name: "no location",
+			responses: map[string]*http.Response{
+				"https://example.com/old": redirect(http.StatusTemporaryRedirect, ""),
+			},
+			expectedRedirects: []Redirect{{URL: "https://example.com/old", Status: http.StatusTemporaryRedirect}},
+			expectedErr:       ErrRedirectNoLocation,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockClient := mocks.NewMockHTTPClient(ctrl)
+			mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				resp, ok := tc.responses[req.URL.String()]
+				assert.True(t, ok, req.URL.String())
+				return resp, nil
+			}).Times(len(tc.responses))
+
+			p, err := New(Config{}, mockClient, nil, nil, nil, nil).ExtractURLs(context.Background(), "https://example.com/old")
+			var statusErr *StatusError
+			if errors.As(tc.expectedErr, &statusErr) {
+				// the chain ending with an error page is recorded along with its final url
+				assert.ErrorAs(t, err, &statusErr)
+				assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+				assert.Equal(t, tc.expectedFinalURL, statusErr.FinalURL)
+				assert.Equal(t, tc.expectedRedirects, statusErr.Redirects)
+				return
+			}
+			if tc.expectedErr != nil {
+				assert.ErrorIs(t, err, tc.expectedErr)
+				var redirectErr *RedirectError
+				assert.ErrorAs(t, err, &redirectErr)
+				assert.Equal(t, tc.expectedRedirects, redirectErr.Redirects)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedFinalURL, p.FinalURL)
+			assert.Equal(t, tc.expectedURLs, p.WebURLs)
+			assert.Equal(t, tc.expectedRedirects, p.Redirects)
+		})
+	}
 }
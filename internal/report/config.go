@@ -0,0 +1,40 @@
+package report
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+const (
+	// FormatJSON writes a json object per line for every page.
+	FormatJSON = "json"
+	// FormatCSV writes a csv row for every page.
+	FormatCSV = "csv"
+)
+
+type Config struct {
+	// FileName is the file to write the report, the report is not written if it is empty
+	FileName string
+	Format   string
+
+	// RedirectsFileName is the file to write redirect issues, the issues are not written if it is empty
+	RedirectsFileName string
+	// MaxRedirectHops is the number of redirect hops after which the chain is reported as too long
+	MaxRedirectHops int
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "ReportConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.StringVar(&c.FileName, "file_name", "", "filename to write results of crawled pages, not written if empty")
+	f.StringVar(&c.Format, "format", FormatJSON, "report format: json or csv")
+	f.StringVar(
+		&c.RedirectsFileName, "redirects_file_name",
+		"", "filename to write redirect issues in the report format, not written if empty",
+	)
+	f.IntVar(&c.MaxRedirectHops, "max_redirect_hops", 1, "redirect chains with more hops are reported as too long")
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
@@ -0,0 +1,112 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/triabokon/goscout/internal/crawler"
+	"github.com/triabokon/goscout/internal/parser"
+)
+
+// Redirect issues that are reported.
+const (
+	IssueLongChain  = "long chain"
+	IssueLoop       = "loop"
+	IssueOutOfScope = "out of scope"
+	// IssueTemporary is reported for temporary redirects that often should be permanent after a site move
+	IssueTemporary = "temporary redirect"
+	// IssueErrorPage is reported for redirects that end with an error page, such as a moved page redirected to 404
+	IssueErrorPage = "redirect to error page"
+)
+
+//go:generate mockgen -destination=./mocks/scope_mock.go -package=mocks github.com/triabokon/goscout/internal/report Scope
+type Scope interface {
+	InScope(u *url.URL) bool
+}
+
+// RedirectIssue is a problem found in the redirect chain of a page.
+type RedirectIssue struct {
+	URL   string `json:"url"`
+	Issue string `json:"issue"`
+	Chain string `json:"chain"`
+}
+
+// RedirectIssues finds redirect chains with more than maxHops hops, loops, redirects out of the crawl scope,
+// temporary redirects and redirects to error pages, ordered by url.
+func RedirectIssues(results crawler.ResultSet, s Scope, maxHops int) ([]*RedirectIssue, error) {
+	var issues []*RedirectIssue
+	err := results.Range(func(r crawler.PageResult) error {
+		if len(r.Redirects) == 0 {
+			return nil
+		}
+		u, chain := r.URL, formatChain(r.Redirects)
+		add := func(issue string) {
+			issues = append(issues, &RedirectIssue{URL: u, Issue: issue, Chain: chain})
+		}
+		if len(r.Redirects) > maxHops {
+			add(IssueLongChain)
+		}
+		last := r.Redirects[len(r.Redirects)-1]
+		if isLoop(r.Redirects) {
+			add(IssueLoop)
+		} else if outOfScope(s, last.Location) {
+			add(IssueOutOfScope)
+		}
+		if r.Status >= http.StatusMultipleChoices {
+			add(IssueErrorPage)
+		}
+		for _, hop := range r.Redirects {
+			if hop.Status != http.StatusMovedPermanently && hop.Status != http.StatusPermanentRedirect {
+				add(IssueTemporary)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read results: %w", err)
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].URL != issues[j].URL {
+			return issues[i].URL < issues[j].URL
+		}
+		return issues[i].Issue < issues[j].Issue
+	})
+	return issues, nil
+}
+
+// formatChain formats the redirect chain as "url -301-> url -302-> url".
+func formatChain(redirects []parser.Redirect) string {
+	var b strings.Builder
+	for _, hop := range redirects {
+		fmt.Fprintf(&b, "%s -%d-> ", hop.URL, hop.Status)
+	}
+	b.WriteString(redirects[len(redirects)-1].Location)
+	return b.String()
+}
+
+// isLoop checks if the last redirect leads to an url of the chain.
+func isLoop(redirects []parser.Redirect) bool {
+	last := redirects[len(redirects)-1].Location
+	for _, hop := range redirects {
+		if hop.URL == last {
+			return true
+		}
+	}
+	return false
+}
+
+// outOfScope checks if the redirect location is out of the crawl scope, so the chain left the site.
+func outOfScope(s Scope, location string) bool {
+	if location == "" {
+		return false
+	}
+	parsedLocation, err := url.Parse(location)
+	if err != nil {
+		return false
+	}
+	return !s.InScope(parsedLocation)
+}
@@ -0,0 +1,188 @@
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/triabokon/goscout/internal/crawler"
+)
+
+// Report writes results of crawled pages, one record per page.
+type Report struct {
+	config Config
+}
+
+// Record is a page result as it is written to the report.
+type Record struct {
+	URL          string `json:"url"`
+	FinalURL     string `json:"final_url,omitempty"`
+	Redirects    string `json:"redirects,omitempty"`
+	Status       int    `json:"status,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+	Size         int64  `json:"size"`
+	Truncated    bool   `json:"truncated,omitempty"`
+	LatencyMs    int64  `json:"latency_ms"`
+	Depth        int    `json:"depth"`
+	Parent       string `json:"parent,omitempty"`
+	FetchedAt    string `json:"fetched_at"`
+	Attempts     int    `json:"attempts"`
+	Links        int    `json:"links"`
+	LastModified string `json:"last_modified,omitempty"`
+	Canonical    string `json:"canonical,omitempty"`
+	NoIndex      bool   `json:"noindex,omitempty"`
+	NoFollow     bool   `json:"nofollow,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+func New(config Config) (*Report, error) {
+	if config.Format != FormatJSON && config.Format != FormatCSV {
+		return nil, fmt.Errorf("unknown report format %q", config.Format)
+	}
+	return &Report{config: config}, nil
+}
+
+// NewRecord converts the page result to the report record.
+func NewRecord(r crawler.PageResult) *Record {
+	record := &Record{
+		URL:         r.URL,
+		FinalURL:    r.FinalURL,
+		Status:      r.Status,
+		ContentType: r.ContentType,
+		Size:        r.Size,
+		Truncated:   r.Truncated,
+		LatencyMs:   r.Latency.Milliseconds(),
+		Depth:       r.Depth,
+		Parent:      r.Parent,
+		FetchedAt:   formatTime(r.FetchedAt),
+		Attempts:    r.Attempts,
+		Links:       len(r.URLs),
+		Canonical:   r.Canonical,
+		NoIndex:     r.NoIndex,
+		NoFollow:    r.NoFollow,
+		Error:       r.Error,
+	}
+	record.LastModified = formatTime(r.LastModified)
+	if len(r.Redirects) != 0 {
+		record.Redirects = formatChain(r.Redirects)
+	}
+	return record
+}
+
+// WriteToFile writes the report of page results to the configured file.
+func (r *Report) WriteToFile(results crawler.ResultSet) error {
+	return writeFile(r.config.FileName, func(w io.Writer) error {
+		return r.Write(w, results)
+	})
+}
+
+// WriteRedirectsToFile writes redirect issues to the configured file.
+func (r *Report) WriteRedirectsToFile(issues []*RedirectIssue) error {
+	return writeFile(r.config.RedirectsFileName, func(w io.Writer) error {
+		return r.WriteRedirects(w, issues)
+	})
+}
+
+// Write writes records of page results in the configured format ordered by depth and url,
+// records are written as results are read, so they are not kept in memory.
+func (r *Report) Write(w io.Writer, results crawler.ResultSet) error {
+	if r.config.Format == FormatCSV {
+		return writeCSV(w, results)
+	}
+	enc := json.NewEncoder(w)
+	return results.Range(func(result crawler.PageResult) error {
+		if err := enc.Encode(NewRecord(result)); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+		return nil
+	})
+}
+
+// WriteRedirects writes redirect issues in the configured format.
+func (r *Report) WriteRedirects(w io.Writer, issues []*RedirectIssue) error {
+	if r.config.Format == FormatCSV {
+		rows := make([][]string, 0, len(issues)+1)
+		rows = append(rows, []string{"url", "issue", "chain"})
+		for _, i := range issues {
+			rows = append(rows, []string{i.URL, i.Issue, i.Chain})
+		}
+		return writeCSVRows(w, rows)
+	}
+	enc := json.NewEncoder(w)
+	for _, i := range issues {
+		if err := enc.Encode(i); err != nil {
+			return fmt.Errorf("failed to write redirect issue: %w", err)
+		}
+	}
+	return nil
+}
+
+func writeCSV(w io.Writer, results crawler.ResultSet) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{
+		"url", "final_url", "redirects", "status", "content_type", "size", "truncated", "latency_ms", "depth",
+		"parent", "fetched_at", "attempts", "links", "last_modified", "canonical", "noindex", "nofollow", "error",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	err = results.Range(func(result crawler.PageResult) error {
+		r := NewRecord(result)
+		return cw.Write([]string{
+			r.URL, r.FinalURL, r.Redirects, strconv.Itoa(r.Status), r.ContentType,
+			strconv.FormatInt(r.Size, 10), strconv.FormatBool(r.Truncated), strconv.FormatInt(r.LatencyMs, 10),
+			strconv.Itoa(r.Depth),
+			r.Parent, r.FetchedAt, strconv.Itoa(r.Attempts), strconv.Itoa(r.Links), r.LastModified,
+			r.Canonical, strconv.FormatBool(r.NoIndex), strconv.FormatBool(r.NoFollow), r.Error,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+func writeCSVRows(w io.Writer, rows [][]string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+// writeFile creates file with filename and writes its content with write.
+func writeFile(filename string, write func(w io.Writer) error) (err error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer func() {
+		if cErr := file.Close(); cErr != nil && err == nil {
+			err = fmt.Errorf("failed to close file: %w", cErr)
+		}
+	}()
+	w := bufio.NewWriter(file)
+	if err = write(w); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,191 @@
+package report_test
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triabokon/goscout/internal/crawler"
+	"github.com/triabokon/goscout/internal/parser"
+	"github.com/triabokon/goscout/internal/report"
+	"github.com/triabokon/goscout/internal/report/mocks"
+	"github.com/triabokon/goscout/internal/results"
+)
+
+// resultSet creates the result set of the page results.
+func resultSet(t *testing.T, pages ...crawler.PageResult) crawler.ResultSet {
+	set := results.NewMemory()
+	for _, p := range pages {
+		require.NoError(t, set.Put(p))
+	}
+	return set
+}
+
+func testResults(t *testing.T) crawler.ResultSet {
+	fetchedAt := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	return resultSet(t,
+		crawler.PageResult{
+			URL:       "https://example.com/missing",
+			Status:    404,
+			Depth:     2,
+			Parent:    "https://example.com",
+			FetchedAt: fetchedAt,
+			Attempts:  1,
+			Error:     "unexpected status 404",
+		},
+		crawler.PageResult{
+			URL:         "https://example.com",
+			FinalURL:    "https://example.com/",
+			Redirects:   []parser.Redirect{{URL: "https://example.com", Status: 301, Location: "https://example.com/"}},
+			Status:      200,
+			ContentType: "text/html",
+			Size:        2048,
+			Truncated:   true,
+			Latency:     120 * time.Millisecond,
+			Depth:       1,
+			FetchedAt:   fetchedAt,
+			Attempts:    2,
+			URLs:        []string{"https://example.com/missing", "https://example.com/logo.png"},
+			Canonical:   "https://example.com/",
+			NoFollow:    true,
+		},
+	)
+}
+
+func TestReport_New(t *testing.T) {
+	_, err := report.New(report.Config{Format: "xml"})
+	assert.EqualError(t, err, `unknown report format "xml"`)
+}
+
+func TestReport_Write(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format: report.FormatJSON,
+			expected: `{"url":"https://example.com","final_url":"https://example.com/",` +
+				`"redirects":"https://example.com -301-\u003e https://example.com/","status":200,` +
+				`"content_type":"text/html","size":2048,"truncated":true,"latency_ms":120,"depth":1,` +
+				`"fetched_at":"2023-05-06T07:08:09Z","attempts":2,"links":2,"canonical":"https://example.com/",` +
+				`"nofollow":true}` + "\n" +
+				`{"url":"https://example.com/missing","status":404,"size":0,"latency_ms":0,"depth":2,` +
+				`"parent":"https://example.com","fetched_at":"2023-05-06T07:08:09Z","attempts":1,"links":0,` +
+				`"error":"unexpected status 404"}` + "\n",
+		},
+		{
+			format: report.FormatCSV,
+			expected: "url,final_url,redirects,status,content_type,size,truncated,latency_ms,depth,parent,fetched_at," +
+				"attempts,links," +
+				"last_modified,canonical,noindex,nofollow,error\n" +
+				"https://example.com,https://example.com/,https://example.com -301-> https://example.com/," +
+				"200,text/html,2048,true,120,1,,2023-05-06T07:08:09Z,2,2,,https://example.com/,false,true,\n" +
+				"https://example.com/missing,,,404,,0,false,0,2,https://example.com,2023-05-06T07:08:09Z,1,0,,,false,false," +
+				"unexpected status 404\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			r, err := report.New(report.Config{Format: tc.format})
+			assert.NoError(t, err)
+			var buf bytes.Buffer
+			assert.NoError(t, r.Write(&buf, testResults(t)))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestReport_WriteToFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "report.csv")
+	r, err := report.New(report.Config{FileName: filename, Format: report.FormatCSV})
+	assert.NoError(t, err)
+	assert.NoError(t, r.WriteToFile(testResults(t)))
+
+	content, err := os.ReadFile(filename)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "https://example.com/missing,,,404")
+}
+
+func TestReport_RedirectIssues(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pages := resultSet(t,
+		crawler.PageResult{URL: "https://example.com/old", Redirects: []parser.Redirect{
+			{URL: "https://example.com/old", Status: 301, Location: "https://example.com/older"},
+			{URL: "https://example.com/older", Status: 302, Location: "https://example.com/new"},
+		}},
+		crawler.PageResult{URL: "https://example.com/loop", Redirects: []parser.Redirect{
+			{URL: "https://example.com/loop", Status: 301, Location: "https://example.com/loop/"},
+			{URL: "https://example.com/loop/", Status: 301, Location: "https://example.com/loop"},
+		}},
+		crawler.PageResult{URL: "https://example.com/shop", Redirects: []parser.Redirect{
+			{URL: "https://example.com/shop", Status: 308, Location: "https://shop.example.com/"},
+		}},
+		// another host of the scope is not out of scope
+		crawler.PageResult{URL: "https://example.com/blog", Redirects: []parser.Redirect{
+			{URL: "https://example.com/blog", Status: 301, Location: "https://blog.example.com/"},
+		}},
+		crawler.PageResult{URL: "https://example.com/moved", Redirects: []parser.Redirect{
+			{URL: "https://example.com/moved", Status: 301, Location: "https://example.com/here"},
+		}},
+		crawler.PageResult{URL: "https://example.com/removed", Status: 404, Redirects: []parser.Redirect{
+			{URL: "https://example.com/removed", Status: 301, Location: "https://example.com/gone"},
+		}},
+		crawler.PageResult{URL: "https://example.com/page"},
+	)
+
+	mockScope := mocks.NewMockScope(ctrl)
+	mockScope.EXPECT().InScope(gomock.Any()).DoAndReturn(func(u *url.URL) bool {
+		return u.Hostname() != "shop.example.com"
+	}).AnyTimes()
+
+	issues, err := report.RedirectIssues(pages, mockScope, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []*report.RedirectIssue{
+		{
+			URL:   "https://example.com/loop",
+			Issue: report.IssueLongChain,
+			Chain: "https://example.com/loop -301-> https://example.com/loop/ -301-> https://example.com/loop",
+		},
+		{
+			URL:   "https://example.com/loop",
+			Issue: report.IssueLoop,
+			Chain: "https://example.com/loop -301-> https://example.com/loop/ -301-> https://example.com/loop",
+		},
+		{
+			URL:   "https://example.com/old",
+			Issue: report.IssueLongChain,
+			Chain: "https://example.com/old -301-> https://example.com/older -302-> https://example.com/new",
+		},
+		{
+			URL:   "https://example.com/old",
+			Issue: report.IssueTemporary,
+			Chain: "https://example.com/old -301-> https://example.com/older -302-> https://example.com/new",
+		},
+		{
+			URL:   "https://example.com/removed",
+			Issue: report.IssueErrorPage,
+			Chain: "https://example.com/removed -301-> https://example.com/gone",
+		},
+		{
+			URL:   "https://example.com/shop",
+			Issue: report.IssueOutOfScope,
+			Chain: "https://example.com/shop -308-> https://shop.example.com/",
+		},
+	}, issues)
+
+	r, err := report.New(report.Config{Format: report.FormatCSV})
+	assert.NoError(t, err)
+	var buf bytes.Buffer
+	assert.NoError(t, r.WriteRedirects(&buf, issues[5:]))
+	assert.Equal(t, "url,issue,chain\nhttps://example.com/shop,out of scope,https://example.com/shop -308-> https://shop.example.com/\n", buf.String())
+}
@@ -0,0 +1,31 @@
+package results
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+// Storages of the result set.
+const (
+	// StorageMemory keeps results in memory.
+	StorageMemory = "memory"
+	// StorageDisk keeps results in a temporary database on disk.
+	StorageDisk = "disk"
+)
+
+type Config struct {
+	Storage string
+	// Dir is the directory of the disk storage, the system temporary directory is used if empty
+	Dir string
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "ResultsConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.StringVar(&c.Storage, "storage", StorageMemory, "storage of crawled page results: memory or disk")
+	f.StringVar(&c.Dir, "dir", "", "directory of the disk storage, the system temporary directory if empty")
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
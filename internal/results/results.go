@@ -0,0 +1,167 @@
+package results
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/triabokon/goscout/internal/boltdb"
+	"github.com/triabokon/goscout/internal/crawler"
+)
+
+const (
+	// bucketResults keeps results by their order keys, so they are iterated by depth and url
+	bucketResults = "results"
+	// bucketKeys maps urls to order keys of their results
+	bucketKeys = "keys"
+)
+
+// Set is a result set that should be closed once results are written.
+type Set interface {
+	crawler.ResultSet
+	io.Closer
+}
+
+// New creates the result set of the configured storage.
+func New(c Config) (Set, error) {
+	switch c.Storage {
+	case StorageMemory:
+		return NewMemory(), nil
+	case StorageDisk:
+		return NewDisk(c.Dir)
+	default:
+		return nil, fmt.Errorf("unknown results storage %q", c.Storage)
+	}
+}
+
+// Memory keeps results in memory.
+type Memory struct {
+	mu      sync.Mutex
+	results map[string]crawler.PageResult
+}
+
+func NewMemory() *Memory {
+	return &Memory{results: make(map[string]crawler.PageResult)}
+}
+
+func (m *Memory) Put(r crawler.PageResult) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[r.URL] = r
+	return nil
+}
+
+func (m *Memory) Range(f func(r crawler.PageResult) error) error {
+	m.mu.Lock()
+	list := make([]crawler.PageResult, 0, len(m.results))
+	for _, r := range m.results {
+		list = append(list, r)
+	}
+	m.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Depth != list[j].Depth {
+			return list[i].Depth < list[j].Depth
+		}
+		return list[i].URL < list[j].URL
+	})
+	for _, r := range list {
+		if err := f(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Len() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.results), nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Disk keeps results in a temporary database, it is removed on Close.
+// The set is not synced to disk, since results are saved to the crawl state on their own.
+type Disk struct {
+	db *boltdb.Temp
+}
+
+// NewDisk creates the database in the directory, the system temporary directory is used if it is empty.
+func NewDisk(dir string) (*Disk, error) {
+	db, err := boltdb.OpenTemp(dir, "results-*.db", bucketResults, bucketKeys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results database: %w", err)
+	}
+	return &Disk{db: db}, nil
+}
+
+func (d *Disk) Put(r crawler.PageResult) error {
+	value, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
+	key := orderKey(r)
+	err = d.db.Update(func(tx *bbolt.Tx) error {
+		results, keys := tx.Bucket([]byte(bucketResults)), tx.Bucket([]byte(bucketKeys))
+		// the result of the url crawled again at another depth replaces the previous one
+		if previous := keys.Get([]byte(r.URL)); previous != nil && string(previous) != key {
+			if dErr := results.Delete(previous); dErr != nil {
+				return fmt.Errorf("failed to delete previous result: %w", dErr)
+			}
+		}
+		if pErr := keys.Put([]byte(r.URL), []byte(key)); pErr != nil {
+			return fmt.Errorf("failed to put key: %w", pErr)
+		}
+		if pErr := results.Put([]byte(key), value); pErr != nil {
+			return fmt.Errorf("failed to put result: %w", pErr)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store result: %w", err)
+	}
+	return nil
+}
+
+func (d *Disk) Range(f func(r crawler.PageResult) error) error {
+	return d.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketResults)).ForEach(func(_, v []byte) error {
+			var r crawler.PageResult
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("failed to unmarshal result: %w", err)
+			}
+			return f(r)
+		})
+	})
+}
+
+func (d *Disk) Len() (int, error) {
+	n := 0
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		n = tx.Bucket([]byte(bucketKeys)).Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count results: %w", err)
+	}
+	return n, nil
+}
+
+// Close closes and removes the database.
+func (d *Disk) Close() error {
+	if err := d.db.Close(); err != nil {
+		return fmt.Errorf("failed to close results database: %w", err)
+	}
+	return nil
+}
+
+// orderKey is the key that orders results by depth and url, depth is zero-padded to keep its order.
+func orderKey(r crawler.PageResult) string {
+	return fmt.Sprintf("%010d %s", r.Depth, r.URL)
+}
@@ -0,0 +1,57 @@
+package results_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triabokon/goscout/internal/crawler"
+	"github.com/triabokon/goscout/internal/results"
+)
+
+func TestSet_PutRange(t *testing.T) {
+	for _, storage := range []string{results.StorageMemory, results.StorageDisk} {
+		t.Run(storage, func(t *testing.T) {
+			s, err := results.New(results.Config{Storage: storage, Dir: t.TempDir()})
+			require.NoError(t, err)
+			defer func() { assert.NoError(t, s.Close()) }()
+
+			for _, r := range []crawler.PageResult{
+				{URL: "https://example.com/b", Depth: 2},
+				{URL: "https://example.com/c", Depth: 10},
+				{URL: "https://example.com", Depth: 1},
+				{URL: "https://example.com/a", Depth: 2, Status: 500},
+				// the page crawled again replaces its previous result
+				{URL: "https://example.com/a", Depth: 3, Status: 200, URLs: []string{"https://example.com/b"}},
+			} {
+				require.NoError(t, s.Put(r))
+			}
+
+			var ranged []crawler.PageResult
+			require.NoError(t, s.Range(func(r crawler.PageResult) error {
+				ranged = append(ranged, r)
+				return nil
+			}))
+			// results are ordered by depth and url
+			assert.Equal(t, []crawler.PageResult{
+				{URL: "https://example.com", Depth: 1},
+				{URL: "https://example.com/b", Depth: 2},
+				{URL: "https://example.com/a", Depth: 3, Status: 200, URLs: []string{"https://example.com/b"}},
+				{URL: "https://example.com/c", Depth: 10},
+			}, ranged)
+			n, err := s.Len()
+			require.NoError(t, err)
+			assert.Equal(t, 4, n)
+
+			stop := fmt.Errorf("stop")
+			assert.ErrorIs(t, s.Range(func(crawler.PageResult) error { return stop }), stop)
+		})
+	}
+}
+
+func TestNew_UnknownStorage(t *testing.T) {
+	_, err := results.New(results.Config{Storage: "cloud"})
+	assert.EqualError(t, err, `unknown results storage "cloud"`)
+}
@@ -0,0 +1,171 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	headerRetryAfter = "Retry-After"
+	// maxDrainSize is the amount of a discarded response body that is read, so the connection could be reused.
+	maxDrainSize = 64 << 10
+)
+
+//go:generate mockgen -destination=./mocks/http_mock.go -package=mocks github.com/triabokon/goscout/internal/retry HTTPClient
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client is an http client that retries transient failures with exponential backoff and jitter.
+type Client struct {
+	config     Config
+	client     HTTPClient
+	retryables map[int]bool
+}
+
+func New(c Config, client HTTPClient) *Client {
+	retryables := make(map[int]bool, len(c.RetryableStatuses))
+	for _, s := range c.RetryableStatuses {
+		retryables[s] = true
+	}
+	return &Client{config: c, client: client, retryables: retryables}
+}
+
+// Do sends the request, retrying network errors and retryable status codes.
+// Number of attempts is added to the attempts counter of the request context, if there is one.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	counter, _ := ctx.Value(attemptsKey{}).(*int64)
+	for attempt := 1; ; attempt++ {
+		if counter != nil {
+			atomic.AddInt64(counter, 1)
+		}
+		r, err := c.request(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := c.client.Do(r)
+		last := attempt >= c.config.MaxAttempts || !c.canRetry(req)
+		switch {
+		case err != nil && (last || ctx.Err() != nil):
+			return nil, fmt.Errorf("failed after %d attempts: %w", attempt, err)
+		case err == nil && (last || !c.retryables[resp.StatusCode]):
+			return resp, nil
+		}
+
+		delay := backoff(c.config, attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get(headerRetryAfter), time.Now()); ok {
+				// the server asks to wait longer than retries are allowed to, so its response is returned
+				if d > c.config.MaxBackoff {
+					return resp, nil
+				}
+				delay = d
+			}
+			Drain(resp.Body)
+		}
+		if wErr := wait(ctx, delay); wErr != nil {
+			return nil, fmt.Errorf("failed to wait for retry: %w", wErr)
+		}
+	}
+}
+
+// canRetry checks if the request body could be sent again.
+func (c *Client) canRetry(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// request returns the request for the attempt, the body of a retried request is recreated.
+func (c *Client) request(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get request body: %w", err)
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
+}
+
+// backoff returns delay before the next attempt, it is chosen randomly
+// between the half and the full exponential delay to spread retries of concurrent requests.
+func backoff(c Config, attempt int) time.Duration {
+	d := c.InitialBackoff
+	for i := 1; i < attempt && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	//nolint:gosec // jitter does not need a secure random generator
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryAfter parses Retry-After header value, that is either delay in seconds or http date.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Drain reads the rest of the discarded response body and closes it, so the connection could be reused.
+func Drain(body io.ReadCloser) {
+	if body == nil {
+		return
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(body, maxDrainSize))
+	_ = body.Close()
+}
+
+type attemptsKey struct{}
+
+// WithAttempts returns context that counts attempts of requests made with it.
+func WithAttempts(ctx context.Context) context.Context {
+	var counter int64
+	return context.WithValue(ctx, attemptsKey{}, &counter)
+}
+
+// Attempts returns number of attempts counted in the context created by WithAttempts.
+func Attempts(ctx context.Context) int {
+	counter, ok := ctx.Value(attemptsKey{}).(*int64)
+	if !ok {
+		return 0
+	}
+	return int(atomic.LoadInt64(counter))
+}
@@ -0,0 +1,158 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triabokon/goscout/internal/retry"
+	"github.com/triabokon/goscout/internal/retry/mocks"
+)
+
+func newResponse(status int, header http.Header) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("body")),
+	}
+}
+
+type result struct {
+	resp *http.Response
+	err  error
+}
+
+func TestClient_Do(t *testing.T) {
+	config := retry.Config{
+		MaxAttempts:       3,
+		InitialBackoff:    time.Millisecond,
+		MaxBackoff:        5 * time.Millisecond,
+		RetryableStatuses: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	}
+	networkErr := errors.New("connection reset")
+
+	testCases := []struct {
+		name             string
+		results          []result
+		expectedStatus   int
+		expectedAttempts int
+		expectedErrorMsg string
+	}{
+		{
+			name:             "success",
+			results:          []result{{resp: newResponse(http.StatusOK, nil)}},
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 1,
+		},
+		{
+			name: "retryable status",
+			results: []result{
+				{resp: newResponse(http.StatusServiceUnavailable, nil)},
+				{resp: newResponse(http.StatusOK, nil)},
+			},
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+		},
+		{
+			name: "retry after",
+			results: []result{
+				{resp: newResponse(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"0"}})},
+				{resp: newResponse(http.StatusOK, nil)},
+			},
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+		},
+		{
+			name: "retry after longer than max backoff",
+			results: []result{
+				{resp: newResponse(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"60"}})},
+			},
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "not retryable status",
+			results:          []result{{resp: newResponse(http.StatusNotFound, nil)}},
+			expectedStatus:   http.StatusNotFound,
+			expectedAttempts: 1,
+		},
+		{
+			name: "attempts exhausted with retryable status",
+			results: []result{
+				{resp: newResponse(http.StatusServiceUnavailable, nil)},
+				{resp: newResponse(http.StatusServiceUnavailable, nil)},
+				{resp: newResponse(http.StatusServiceUnavailable, nil)},
+			},
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedAttempts: 3,
+		},
+		{
+			name: "network error",
+			results: []result{
+				{err: networkErr},
+				{resp: newResponse(http.StatusOK, nil)},
+			},
+			expectedStatus:   http.StatusOK,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "attempts exhausted with network error",
+			results:          []result{{err: networkErr}, {err: networkErr}, {err: networkErr}},
+			expectedAttempts: 3,
+			expectedErrorMsg: "failed after 3 attempts: connection reset",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			httpClient := mocks.NewMockHTTPClient(ctrl)
+			calls := make([]*gomock.Call, 0, len(tc.results))
+			for _, r := range tc.results {
+				calls = append(calls, httpClient.EXPECT().Do(gomock.Any()).Return(r.resp, r.err))
+			}
+			gomock.InOrder(calls...)
+
+			ctx := retry.WithAttempts(context.Background())
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", http.NoBody)
+			assert.NoError(t, err)
+			resp, err := retry.New(config, httpClient).Do(req)
+			assert.Equal(t, tc.expectedAttempts, retry.Attempts(ctx))
+			if tc.expectedErrorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedErrorMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode)
+		})
+	}
+}
+
+func TestClient_DoCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	httpClient := mocks.NewMockHTTPClient(ctrl)
+	config := retry.Config{
+		MaxAttempts:       3,
+		InitialBackoff:    time.Hour,
+		MaxBackoff:        time.Hour,
+		RetryableStatuses: []int{http.StatusServiceUnavailable},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	httpClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(*http.Request) (*http.Response, error) {
+		cancel()
+		return newResponse(http.StatusServiceUnavailable, nil), nil
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://example.com", http.NoBody)
+	assert.NoError(t, err)
+	_, err = retry.New(config, httpClient).Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+}
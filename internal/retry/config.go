@@ -0,0 +1,41 @@
+package retry
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+type Config struct {
+	// MaxAttempts is the total number of attempts including the first one, 1 disables retries
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryableStatuses are response status codes that are retried
+	RetryableStatuses []int
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "RetryConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.IntVar(&c.MaxAttempts, "max_attempts", 3, "maximum number of attempts to fetch a url, 1 disables retries")
+	f.DurationVar(
+		&c.InitialBackoff, "initial_backoff",
+		500*time.Millisecond, "delay before the first retry, it is doubled for every next retry",
+	)
+	f.DurationVar(&c.MaxBackoff, "max_backoff", 30*time.Second, "maximum delay between retries")
+	f.IntSliceVar(
+		&c.RetryableStatuses, "statuses",
+		[]int{
+			http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout,
+		},
+		"response status codes that are retried",
+	)
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
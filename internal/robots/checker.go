@@ -0,0 +1,166 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	robotsPath = "/robots.txt"
+	// maxFileSize is the amount of robots.txt content that is parsed, the rest is ignored.
+	maxFileSize = 500 << 10
+)
+
+//go:generate mockgen -destination=./mocks/http_mock.go -package=mocks github.com/triabokon/goscout/internal/robots HTTPClient
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Checker fetches robots.txt once per host and checks urls against it.
+type Checker struct {
+	config Config
+	client HTTPClient
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// host keeps robots.txt rules of a single host and the time when it could be requested next.
+type host struct {
+	// fetchMu guards fetching robots.txt, which is fetched again if it was interrupted by the context
+	fetchMu sync.Mutex
+	fetched bool
+	group   *Group
+	// disallowAll is set when robots.txt is unreachable, so the whole host must not be crawled
+	disallowAll bool
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func New(c Config, client HTTPClient) *Checker {
+	return &Checker{
+		config: c,
+		client: client,
+		hosts:  make(map[string]*host),
+	}
+}
+
+// Allowed checks if the url may be crawled according to the robots.txt of its host.
+func (c *Checker) Allowed(ctx context.Context, u string) (bool, error) {
+	if !c.config.Enabled {
+		return true, nil
+	}
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse url: %w", err)
+	}
+	if parsedURL.Path == robotsPath {
+		return true, nil
+	}
+	h, err := c.host(ctx, parsedURL)
+	if err != nil {
+		return false, err
+	}
+	if h.disallowAll {
+		return false, nil
+	}
+	return h.group.Allowed(parsedURL.RequestURI()), nil
+}
+
+// Wait blocks until the crawl-delay of the url host has passed since the previous request to it.
+func (c *Checker) Wait(ctx context.Context, u string) error {
+	if !c.config.Enabled {
+		return nil
+	}
+	parsedURL, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf("failed to parse url: %w", err)
+	}
+	h, err := c.host(ctx, parsedURL)
+	if err != nil {
+		return err
+	}
+	if h.group == nil || h.group.CrawlDelay == 0 {
+		return nil
+	}
+	// reserve the next request slot, so concurrent callers are spread by crawl-delay
+	h.mu.Lock()
+	now := time.Now()
+	if h.next.Before(now) {
+		h.next = now
+	}
+	wait := h.next.Sub(now)
+	h.next = h.next.Add(h.group.CrawlDelay)
+	h.mu.Unlock()
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// host returns rules of the url host, fetching robots.txt on the first call.
+func (c *Checker) host(ctx context.Context, u *url.URL) (*host, error) {
+	origin := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	h, ok := c.hosts[origin]
+	if !ok {
+		h = &host{}
+		c.hosts[origin] = h
+	}
+	c.mu.Unlock()
+
+	h.fetchMu.Lock()
+	defer h.fetchMu.Unlock()
+	if h.fetched {
+		return h, nil
+	}
+	group, disallowAll := c.fetch(ctx, origin+robotsPath)
+	// robots.txt that was not fetched because the crawl is stopped is not kept as unreachable
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	h.group, h.disallowAll, h.fetched = group, disallowAll, true
+	return h, nil
+}
+
+// fetch gets robots.txt and returns the group for the configured user agent.
+// Following RFC 9309 missing robots.txt allows everything, while unreachable one disallows everything.
+func (c *Checker) fetch(ctx context.Context, u string) (g *Group, disallowAll bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, true
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, true
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return nil, true
+	case resp.StatusCode >= http.StatusBadRequest:
+		return nil, false
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		// redirects are followed by the client, so any left are treated as missing robots.txt
+		return nil, false
+	}
+	f, err := Parse(io.LimitReader(resp.Body, maxFileSize))
+	if err != nil {
+		return nil, true
+	}
+	return f.Group(c.config.UserAgent), false
+}
@@ -0,0 +1,153 @@
+package robots_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triabokon/goscout/internal/robots"
+	"github.com/triabokon/goscout/internal/robots/mocks"
+)
+
+func newResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+// expectFetch expects robots.txt of example.com to be requested with the context of the caller.
+func expectFetch(t *testing.T, client *mocks.MockHTTPClient, resp *http.Response, err error) *gomock.Call {
+	return client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "https://example.com/robots.txt", req.URL.String())
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return resp, err
+	})
+}
+
+func TestChecker_Allowed(t *testing.T) {
+	config := robots.Config{Enabled: true, UserAgent: "goscout"}
+
+	testCases := []struct {
+		name     string
+		resp     *http.Response
+		err      error
+		expected map[string]bool
+	}{
+		{
+			name: "disallowed path",
+			resp: newResponse(http.StatusOK, "User-agent: *\nDisallow: /admin"),
+			expected: map[string]bool{
+				"https://example.com/page":        true,
+				"https://example.com/admin/users": false,
+				"https://example.com/robots.txt":  true,
+			},
+		},
+		{
+			name: "missing robots.txt",
+			resp: newResponse(http.StatusNotFound, ""),
+			expected: map[string]bool{
+				"https://example.com/admin": true,
+			},
+		},
+		{
+			name: "server error",
+			resp: newResponse(http.StatusServiceUnavailable, ""),
+			expected: map[string]bool{
+				"https://example.com/page": false,
+			},
+		},
+		{
+			name: "network error",
+			err:  fmt.Errorf("connection refused"),
+			expected: map[string]bool{
+				"https://example.com/page": false,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client := mocks.NewMockHTTPClient(ctrl)
+			// robots.txt should be fetched only once per host
+			expectFetch(t, client, tc.resp, tc.err).Times(1)
+
+			c := robots.New(config, client)
+			for u, expected := range tc.expected {
+				allowed, err := c.Allowed(context.Background(), u)
+				assert.NoError(t, err)
+				assert.Equal(t, expected, allowed, u)
+			}
+		})
+	}
+
+	t.Run("cancelled fetch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := mocks.NewMockHTTPClient(ctrl)
+		expectFetch(t, client, newResponse(http.StatusOK, "User-agent: *\nDisallow: /admin"), nil).Times(2)
+
+		c := robots.New(config, client)
+		// robots.txt interrupted by the context is fetched again instead of disallowing the host
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := c.Allowed(ctx, "https://example.com/page")
+		assert.ErrorIs(t, err, context.Canceled)
+		allowed, err := c.Allowed(context.Background(), "https://example.com/page")
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		c := robots.New(robots.Config{}, mocks.NewMockHTTPClient(ctrl))
+		allowed, err := c.Allowed(context.Background(), "https://example.com/admin")
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+	})
+
+	t.Run("invalid url", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		c := robots.New(config, mocks.NewMockHTTPClient(ctrl))
+		_, err := c.Allowed(context.Background(), ":")
+		assert.Error(t, err)
+	})
+}
+
+func TestChecker_Wait(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	client := mocks.NewMockHTTPClient(ctrl)
+	expectFetch(t, client, newResponse(http.StatusOK, "User-agent: *\nCrawl-delay: 0.05"), nil)
+
+	c := robots.New(robots.Config{Enabled: true, UserAgent: "goscout"}, client)
+	ctx := context.Background()
+
+	started := time.Now()
+	assert.NoError(t, c.Wait(ctx, "https://example.com/a"))
+	assert.NoError(t, c.Wait(ctx, "https://example.com/b"))
+	assert.NoError(t, c.Wait(ctx, "https://example.com/c"))
+	assert.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.ErrorIs(t, c.Wait(cancelled, "https://example.com/d"), context.Canceled)
+}
@@ -0,0 +1,23 @@
+package robots
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+type Config struct {
+	Enabled bool
+	// UserAgent is the user agent of requests, its product token selects the robots.txt group,
+	// it is not a flag, so the group always matches the user agent the site sees
+	UserAgent string
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "RobotsConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.BoolVar(&c.Enabled, "enabled", true, "respect robots.txt rules and crawl-delay")
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
@@ -0,0 +1,212 @@
+package robots
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	directiveUserAgent  = "user-agent"
+	directiveAllow      = "allow"
+	directiveDisallow   = "disallow"
+	directiveCrawlDelay = "crawl-delay"
+
+	wildcardAgent = "*"
+	// browserProduct starts user agents that mimic browsers, they name the crawler in the compatibleComment
+	browserProduct    = "mozilla/"
+	compatibleComment = "(compatible;"
+	// defaultProductToken selects the group if the user agent names no crawler
+	defaultProductToken = "goscout"
+)
+
+// File is a parsed robots.txt file.
+type File struct {
+	Groups []*Group
+}
+
+// Group is a set of rules that applies to the listed user agents.
+type Group struct {
+	Agents     []string
+	Rules      []Rule
+	CrawlDelay time.Duration
+}
+
+// Rule is a single allow or disallow path pattern.
+type Rule struct {
+	Allow   bool
+	Pattern string
+}
+
+// Parse reads robots.txt content, unknown directives and malformed lines are ignored.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	var current *Group
+	// lastAgent tells if the previous directive was a user-agent line,
+	// consecutive user-agent lines belong to the same group
+	lastAgent := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := parseLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		if key == directiveUserAgent {
+			if current == nil || !lastAgent {
+				current = &Group{}
+				f.Groups = append(f.Groups, current)
+			}
+			current.Agents = append(current.Agents, strings.ToLower(value))
+			lastAgent = true
+			continue
+		}
+		lastAgent = false
+		// rules outside any group are ignored
+		if current == nil {
+			continue
+		}
+		switch key {
+		case directiveAllow, directiveDisallow:
+			// an empty pattern matches nothing
+			if value != "" {
+				current.Rules = append(current.Rules, Rule{Allow: key == directiveAllow, Pattern: value})
+			}
+		case directiveCrawlDelay:
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.CrawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read robots.txt: %w", err)
+	}
+	return f, nil
+}
+
+// parseLine splits robots.txt line into lowercase directive and its value, stripping comments.
+func parseLine(line string) (key, value string, ok bool) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	key, value, ok = strings.Cut(line, ":")
+	if !ok {
+		return "", "", false
+	}
+	return strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value), true
+}
+
+// Group returns the group for the user agent merged from all matching groups.
+// Groups for the specific agent take precedence over the wildcard ones, nil is returned if nothing matches.
+func (f *File) Group(userAgent string) *Group {
+	token := productToken(userAgent)
+	var specific, wildcard *Group
+	for _, g := range f.Groups {
+		switch {
+		case g.hasAgent(token):
+			specific = mergeGroups(specific, g)
+		case g.hasAgent(wildcardAgent):
+			wildcard = mergeGroups(wildcard, g)
+		}
+	}
+	if specific != nil {
+		return specific
+	}
+	return wildcard
+}
+
+// productToken returns lowercase product name of the user agent, e.g. "goscout" for "GoScout/1.0".
+// Browser-like user agents name the crawler in the compatible comment, e.g. "goscout" for
+// "Mozilla/5.0 (compatible; goscout/1.0; +https://github.com/triabokon/goscout)",
+// the default token is returned if the user agent names no crawler.
+func productToken(userAgent string) string {
+	userAgent = strings.TrimSpace(userAgent)
+	if strings.HasPrefix(strings.ToLower(userAgent), browserProduct) {
+		_, comment, found := strings.Cut(userAgent, compatibleComment)
+		if !found {
+			return defaultProductToken
+		}
+		userAgent = strings.TrimSpace(comment)
+	}
+	token, _, _ := strings.Cut(userAgent, "/")
+	token, _, _ = strings.Cut(token, " ")
+	token, _, _ = strings.Cut(token, ";")
+	token, _, _ = strings.Cut(token, ")")
+	if token == "" {
+		return defaultProductToken
+	}
+	return strings.ToLower(token)
+}
+
+func (g *Group) hasAgent(agent string) bool {
+	for _, a := range g.Agents {
+		if a == agent {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeGroups(dst, src *Group) *Group {
+	if dst == nil {
+		dst = &Group{}
+	}
+	dst.Agents = append(dst.Agents, src.Agents...)
+	dst.Rules = append(dst.Rules, src.Rules...)
+	if src.CrawlDelay > dst.CrawlDelay {
+		dst.CrawlDelay = src.CrawlDelay
+	}
+	return dst
+}
+
+// Allowed checks if the path (with query) may be crawled.
+// The longest matching rule wins, allow wins if matching allow and disallow rules have the same length.
+func (g *Group) Allowed(path string) bool {
+	if g == nil {
+		return true
+	}
+	allowed := true
+	longest := -1
+	for _, r := range g.Rules {
+		if !matchPattern(r.Pattern, path) {
+			continue
+		}
+		if l := len(r.Pattern); l > longest || (l == longest && r.Allow) {
+			longest = l
+			allowed = r.Allow
+		}
+	}
+	return allowed
+}
+
+// matchPattern matches the path against robots.txt pattern,
+// where "*" matches any sequence of characters and "$" at the end anchors the pattern to the end of the path.
+func matchPattern(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+	parts := strings.Split(pattern, "*")
+	// the first part must be a prefix of the path
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, part)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(part):]
+	}
+	last := parts[len(parts)-1]
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
@@ -0,0 +1,145 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testRobots = `
+# comment line
+User-agent: *
+Disallow: /admin/
+Allow: /admin/public/
+Disallow: /*.pdf$
+Crawl-delay: 2
+
+User-agent: GoScout
+User-agent: otherbot
+Disallow: /private # trailing comment
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestRobots_Parse(t *testing.T) {
+	f, err := Parse(strings.NewReader(testRobots))
+	assert.NoError(t, err)
+	assert.Equal(t, []*Group{
+		{
+			Agents: []string{"*"},
+			Rules: []Rule{
+				{Allow: false, Pattern: "/admin/"},
+				{Allow: true, Pattern: "/admin/public/"},
+				{Allow: false, Pattern: "/*.pdf$"},
+			},
+			CrawlDelay: 2 * time.Second,
+		},
+		{
+			Agents:     []string{"goscout", "otherbot"},
+			Rules:      []Rule{{Allow: false, Pattern: "/private"}},
+			CrawlDelay: 500 * time.Millisecond,
+		},
+	}, f.Groups)
+}
+
+func TestRobots_Group(t *testing.T) {
+	f, err := Parse(strings.NewReader(testRobots))
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		userAgent     string
+		expectedRules []Rule
+	}{
+		{
+			name:          "specific agent",
+			userAgent:     "GoScout/1.0 (+https://example.com)",
+			expectedRules: []Rule{{Allow: false, Pattern: "/private"}},
+		},
+		{
+			name:      "wildcard agent",
+			userAgent: "somebot",
+			expectedRules: []Rule{
+				{Allow: false, Pattern: "/admin/"},
+				{Allow: true, Pattern: "/admin/public/"},
+				{Allow: false, Pattern: "/*.pdf$"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expectedRules, f.Group(tc.userAgent).Rules)
+		})
+	}
+
+	t.Run("no matching group", func(t *testing.T) {
+		empty, pErr := Parse(strings.NewReader("User-agent: otherbot\nDisallow: /"))
+		assert.NoError(t, pErr)
+		assert.Nil(t, empty.Group("goscout"))
+	})
+}
+
+func TestRobots_ProductToken(t *testing.T) {
+	for userAgent, expected := range map[string]string{
+		"GoScout/1.0 (+https://example.com)": "goscout",
+		"otherbot":                           "otherbot",
+		"Mozilla/5.0 (compatible; goscout/1.0; +https://github.com/triabokon/goscout)": "goscout",
+		"Mozilla/5.0 (compatible; OtherBot; +https://example.com)":                     "otherbot",
+		"Mozilla/5.0 (X11; Linux x86_64) Firefox/118.0":                                "goscout",
+		"": "goscout",
+	} {
+		assert.Equal(t, expected, productToken(userAgent), userAgent)
+	}
+}
+
+func TestRobots_Allowed(t *testing.T) {
+	f, err := Parse(strings.NewReader(testRobots))
+	assert.NoError(t, err)
+	g := f.Group("somebot")
+
+	testCases := []struct {
+		name     string
+		path     string
+		expected bool
+	}{
+		{name: "no matching rules", path: "/page", expected: true},
+		{name: "disallowed prefix", path: "/admin/users", expected: false},
+		{name: "longer allow wins", path: "/admin/public/page", expected: true},
+		{name: "anchored wildcard", path: "/docs/file.pdf", expected: false},
+		{name: "anchored wildcard with query", path: "/docs/file.pdf?download=1", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, g.Allowed(tc.path))
+		})
+	}
+}
+
+func TestRobots_MatchPattern(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{name: "prefix", pattern: "/fish", path: "/fish.html", expected: true},
+		{name: "prefix mismatch", pattern: "/fish", path: "/Fish.html", expected: false},
+		{name: "wildcard", pattern: "/fish*.php", path: "/fishheads/catfish.php?parameters", expected: true},
+		{name: "wildcard mismatch", pattern: "/fish*.php", path: "/fish.asp", expected: false},
+		{name: "anchored", pattern: "/*.php$", path: "/folder/filename.php", expected: true},
+		{name: "anchored mismatch", pattern: "/*.php$", path: "/filename.php5", expected: false},
+		{name: "multiple wildcards", pattern: "/*/p*s$", path: "/a/pages", expected: true},
+		{name: "match all", pattern: "*", path: "/anything", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, matchPattern(tc.pattern, tc.path))
+		})
+	}
+}
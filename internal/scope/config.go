@@ -0,0 +1,54 @@
+package scope
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+// Scheme policies.
+const (
+	// SchemeHTTPS allows only https urls.
+	SchemeHTTPS = "https"
+	// SchemeHTTP allows only http urls.
+	SchemeHTTP = "http"
+	// SchemeBoth allows http and https urls, the same path on different schemes are different urls.
+	SchemeBoth = "both"
+	// SchemeUpgrade allows http and https urls, http urls are upgraded to https if their host serves https.
+	SchemeUpgrade = "upgrade"
+)
+
+type Config struct {
+	// Scheme is the scheme policy, the site url scheme is allowed if empty
+	Scheme string
+	// Hosts are allowed hosts, "*." prefix allows any subdomain, the site host is used if empty
+	Hosts        []string
+	PathPrefixes []string
+	Include      []string
+	Exclude      []string
+	// File has scope rules in addition to the ones from flags
+	File string
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "ScopeConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.StringVar(
+		&c.Scheme, "scheme",
+		"", "url scheme policy: https, http, both or upgrade to upgrade http urls of hosts serving https (default site url scheme)",
+	)
+	f.StringArrayVar(
+		&c.Hosts, "host",
+		nil, "allowed host, *.example.com allows any subdomain of example.com (default site url host)",
+	)
+	f.StringArrayVar(&c.PathPrefixes, "path_prefix", nil, "allowed url path prefix, any path is allowed if not set")
+	f.StringArrayVar(&c.Include, "include", nil, "regexp of urls to crawl, if set urls should match at least one of them")
+	f.StringArrayVar(&c.Exclude, "exclude", nil, "regexp of urls not to crawl")
+	f.StringVar(
+		&c.File, "file",
+		"", "file with scope rules, one \"<host|path_prefix|include|exclude> <value>\" rule per line, # starts a comment",
+	)
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
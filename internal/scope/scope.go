@@ -0,0 +1,196 @@
+package scope
+
+import (
+	"bufio"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Rules of the scope file, they are named the same as flags.
+const (
+	ruleHost       = "host"
+	rulePathPrefix = "path_prefix"
+	ruleInclude    = "include"
+	ruleExclude    = "exclude"
+
+	commentPrefix = "#"
+	// wildcardPrefix allows any subdomain of the host
+	wildcardPrefix = "*."
+)
+
+// ValidateHost checks that the host pattern is not empty and has wildcard only as *. prefix.
+func ValidateHost(pattern string) error {
+	if pattern == "" || strings.Contains(strings.TrimPrefix(pattern, wildcardPrefix), "*") {
+		return fmt.Errorf("invalid host %q, wildcard is only allowed as *. prefix", pattern)
+	}
+	return nil
+}
+
+// MatchHost checks the host against the lowercase host pattern, wildcard pattern does not match the domain itself.
+func MatchHost(pattern, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix)
+	}
+	return host == pattern
+}
+
+// Scope decides which urls belong to the crawl.
+// A url is in scope if its scheme and host are allowed, its path has one of the allowed prefixes,
+// it matches one of the include regexps and none of the exclude regexps, empty lists allow any url.
+type Scope struct {
+	scheme       string
+	hosts        []string
+	pathPrefixes []string
+	include      []*regexp.Regexp
+	exclude      []*regexp.Regexp
+}
+
+// New creates a scope from the config and rules of its file, hosts default to the host of the site url.
+func New(c Config, siteURL string) (*Scope, error) {
+	if c.File != "" {
+		if err := readFile(&c); err != nil {
+			return nil, fmt.Errorf("failed to read scope file: %w", err)
+		}
+	}
+	site, err := url.Parse(siteURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse site url: %w", err)
+	}
+	s := &Scope{scheme: strings.ToLower(c.Scheme), pathPrefixes: c.PathPrefixes}
+	switch s.scheme {
+	case SchemeHTTPS, SchemeHTTP, SchemeBoth, SchemeUpgrade:
+	case "":
+		s.scheme = strings.ToLower(site.Scheme)
+		if s.scheme != SchemeHTTPS && s.scheme != SchemeHTTP {
+			return nil, fmt.Errorf("site url has invalid scheme %q", site.Scheme)
+		}
+	default:
+		return nil, fmt.Errorf("unknown scheme policy %q", c.Scheme)
+	}
+	for _, h := range c.Hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if err = ValidateHost(h); err != nil {
+			return nil, err
+		}
+		s.hosts = append(s.hosts, h)
+	}
+	if len(s.hosts) == 0 {
+		s.hosts = []string{strings.ToLower(site.Hostname())}
+	}
+	if s.include, err = compile(c.Include); err != nil {
+		return nil, fmt.Errorf("failed to compile include regexp: %w", err)
+	}
+	if s.exclude, err = compile(c.Exclude); err != nil {
+		return nil, fmt.Errorf("failed to compile exclude regexp: %w", err)
+	}
+	return s, nil
+}
+
+// InScope checks if the url belongs to the crawl.
+func (s *Scope) InScope(u *url.URL) bool {
+	if !s.schemeAllowed(u.Scheme) || !s.hostAllowed(u.Hostname()) {
+		return false
+	}
+	if len(s.pathPrefixes) != 0 && !s.pathAllowed(u.Path) {
+		return false
+	}
+	str := u.String()
+	if len(s.include) != 0 && !matchAny(s.include, str) {
+		return false
+	}
+	return !matchAny(s.exclude, str)
+}
+
+func (s *Scope) schemeAllowed(scheme string) bool {
+	scheme = strings.ToLower(scheme)
+	switch s.scheme {
+	case SchemeBoth, SchemeUpgrade:
+		return scheme == SchemeHTTPS || scheme == SchemeHTTP
+	default:
+		return scheme == s.scheme
+	}
+}
+
+func (s *Scope) hostAllowed(host string) bool {
+	for _, h := range s.hosts {
+		if MatchHost(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scope) pathAllowed(p string) bool {
+	if p == "" {
+		p = "/"
+	}
+	for _, prefix := range s.pathPrefixes {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(regexps []*regexp.Regexp, s string) bool {
+	for _, re := range regexps {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func compile(exprs []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+// readFile adds rules of the scope file to the config, empty lines and comment lines are ignored.
+func readFile(c *Config) error {
+	file, err := os.Open(c.File)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, commentPrefix) {
+			continue
+		}
+		rule, value, _ := strings.Cut(text, " ")
+		value = strings.TrimSpace(value)
+		if value == "" {
+			return fmt.Errorf("rule on line %d has no value", line)
+		}
+		switch rule {
+		case ruleHost:
+			c.Hosts = append(c.Hosts, value)
+		case rulePathPrefix:
+			c.PathPrefixes = append(c.PathPrefixes, value)
+		case ruleInclude:
+			c.Include = append(c.Include, value)
+		case ruleExclude:
+			c.Exclude = append(c.Exclude, value)
+		default:
+			return fmt.Errorf("unknown rule %q on line %d", rule, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return fmt.Errorf("failed to scan file: %w", err)
+	}
+	return nil
+}
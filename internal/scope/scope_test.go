@@ -0,0 +1,176 @@
+package scope_test
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triabokon/goscout/internal/scope"
+)
+
+func TestScope_New(t *testing.T) {
+	testCases := []struct {
+		name             string
+		config           scope.Config
+		file             string
+		expectedErrorMsg string
+	}{
+		{
+			name:   "valid config",
+			config: scope.Config{Hosts: []string{"*.example.com"}, Include: []string{"/blog/"}},
+		},
+		{
+			name:             "unknown scheme policy",
+			config:           scope.Config{Scheme: "ftp"},
+			expectedErrorMsg: `unknown scheme policy "ftp"`,
+		},
+		{
+			name:             "wildcard inside host",
+			config:           scope.Config{Hosts: []string{"www.*.example.com"}},
+			expectedErrorMsg: "wildcard is only allowed as *. prefix",
+		},
+		{
+			name:             "invalid include regexp",
+			config:           scope.Config{Include: []string{"(blog"}},
+			expectedErrorMsg: "failed to compile include regexp",
+		},
+		{
+			name:             "invalid exclude regexp",
+			config:           scope.Config{Exclude: []string{"(blog"}},
+			expectedErrorMsg: "failed to compile exclude regexp",
+		},
+		{
+			name:             "unknown file rule",
+			file:             "# comment\nhost example.com\nallow /blog/\n",
+			expectedErrorMsg: `unknown rule "allow" on line 3`,
+		},
+		{
+			name:             "file rule without value",
+			file:             "path_prefix\n",
+			expectedErrorMsg: "rule on line 1 has no value",
+		},
+		{
+			name:             "missing file",
+			config:           scope.Config{File: filepath.Join(t.TempDir(), "missing")},
+			expectedErrorMsg: "failed to read scope file",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.file != "" {
+				tc.config.File = filepath.Join(t.TempDir(), "scope.txt")
+				require.NoError(t, os.WriteFile(tc.config.File, []byte(tc.file), 0o600))
+			}
+			_, err := scope.New(tc.config, "https://example.com")
+			if tc.expectedErrorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tc.expectedErrorMsg)
+		})
+	}
+}
+
+func TestScope_InScope(t *testing.T) {
+	testCases := []struct {
+		name     string
+		config   scope.Config
+		file     string
+		inScope  []string
+		outScope []string
+	}{
+		{
+			name:     "site host by default",
+			inScope:  []string{"https://example.com/", "https://EXAMPLE.com/a", "https://example.com:8443/a"},
+			outScope: []string{"https://www.example.com/", "https://example.org/"},
+		},
+		{
+			name:     "site url scheme by default",
+			inScope:  []string{"https://example.com/"},
+			outScope: []string{"http://example.com/", "ftp://example.com/"},
+		},
+		{
+			name:     "http scheme",
+			config:   scope.Config{Scheme: scope.SchemeHTTP},
+			inScope:  []string{"http://example.com/", "HTTP://example.com/a"},
+			outScope: []string{"https://example.com/"},
+		},
+		{
+			name:     "both schemes",
+			config:   scope.Config{Scheme: scope.SchemeBoth},
+			inScope:  []string{"http://example.com/", "https://example.com/"},
+			outScope: []string{"ftp://example.com/"},
+		},
+		{
+			name:     "upgrade scheme",
+			config:   scope.Config{Scheme: scope.SchemeUpgrade},
+			inScope:  []string{"http://example.com/", "https://example.com/"},
+			outScope: []string{"ftp://example.com/"},
+		},
+		{
+			name:     "wildcard subdomains",
+			config:   scope.Config{Hosts: []string{"example.com", "*.Example.com"}},
+			inScope:  []string{"https://example.com/", "https://www.example.com/", "https://a.b.example.com/"},
+			outScope: []string{"https://notexample.com/", "https://example.com.evil.org/"},
+		},
+		{
+			name:     "path prefixes",
+			config:   scope.Config{PathPrefixes: []string{"/blog/", "/docs"}},
+			inScope:  []string{"https://example.com/blog/", "https://example.com/blog/post", "https://example.com/docs-v2"},
+			outScope: []string{"https://example.com/", "https://example.com/blog", "https://example.com/about/blog/"},
+		},
+		{
+			name: "include and exclude",
+			config: scope.Config{
+				Include: []string{`/blog/`, `\?page=\d+$`},
+				Exclude: []string{`/blog/drafts/`, `\.pdf$`},
+			},
+			inScope:  []string{"https://example.com/blog/post", "https://example.com/news?page=2"},
+			outScope: []string{"https://example.com/news", "https://example.com/blog/drafts/post", "https://example.com/blog/a.pdf"},
+		},
+		{
+			name:     "file rules are added to flags",
+			config:   scope.Config{Hosts: []string{"www.example.com"}},
+			file:     "# section of the site\nhost example.com\npath_prefix /blog/\nexclude   /blog/tag/\n\n",
+			inScope:  []string{"https://www.example.com/blog/post", "https://example.com/blog/post"},
+			outScope: []string{"https://example.com/about", "https://example.com/blog/tag/go"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.file != "" {
+				tc.config.File = filepath.Join(t.TempDir(), "scope.txt")
+				require.NoError(t, os.WriteFile(tc.config.File, []byte(tc.file), 0o600))
+			}
+			s, err := scope.New(tc.config, "https://example.com/")
+			require.NoError(t, err)
+			for _, u := range tc.inScope {
+				parsed, pErr := url.Parse(u)
+				require.NoError(t, pErr)
+				assert.True(t, s.InScope(parsed), u)
+			}
+			for _, u := range tc.outScope {
+				parsed, pErr := url.Parse(u)
+				require.NoError(t, pErr)
+				assert.False(t, s.InScope(parsed), u)
+			}
+		})
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	assert.True(t, scope.MatchHost("example.com", "EXAMPLE.com."))
+	assert.True(t, scope.MatchHost("*.example.com", "a.b.example.com"))
+	assert.False(t, scope.MatchHost("*.example.com", "example.com"))
+	assert.False(t, scope.MatchHost("*.example.com", "notexample.com"))
+
+	assert.NoError(t, scope.ValidateHost("*.example.com"))
+	assert.Error(t, scope.ValidateHost("a.*.example.com"))
+	assert.Error(t, scope.ValidateHost(""))
+}
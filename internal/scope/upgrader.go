@@ -0,0 +1,113 @@
+package scope
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultHTTPPort is removed from upgraded urls, urls with other ports are not upgraded.
+const defaultHTTPPort = "80"
+
+//go:generate mockgen -destination=./mocks/http_mock.go -package=mocks github.com/triabokon/goscout/internal/scope HTTPClient
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Upgrader upgrades http urls to https if their host serves https, so both variants are crawled once by https url.
+// Whether the host serves https is checked once per host, so only urls of the crawl scope should be upgraded.
+type Upgrader struct {
+	client HTTPClient
+	// hosts maps host to its probe, it is guarded by mu
+	mu    sync.Mutex
+	hosts map[string]*probe
+}
+
+// probe is the result of the https check of the host, mu is held while the host is probed,
+// so concurrent urls of the host wait for a single probe.
+type probe struct {
+	mu    sync.Mutex
+	done  bool
+	https bool
+}
+
+func NewUpgrader(client HTTPClient) *Upgrader {
+	return &Upgrader{client: client, hosts: make(map[string]*probe)}
+}
+
+// Upgrade returns https copy of the http url if its host serves https, otherwise the url is returned as it is.
+func (u *Upgrader) Upgrade(ctx context.Context, target *url.URL) *url.URL {
+	if !strings.EqualFold(target.Scheme, SchemeHTTP) || (target.Port() != "" && target.Port() != defaultHTTPPort) {
+		return target
+	}
+	host := strings.TrimSuffix(strings.ToLower(target.Host), ":"+defaultHTTPPort)
+	if !u.servesHTTPS(ctx, host) {
+		return target
+	}
+	upgraded := *target
+	upgraded.Scheme = SchemeHTTPS
+	upgraded.Host = host
+	return &upgraded
+}
+
+// UpgradedHosts returns sorted hosts which http urls were upgraded to https.
+func (u *Upgrader) UpgradedHosts() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var hosts []string
+	for host, p := range u.hosts {
+		if p.servesHTTPS() {
+			hosts = append(hosts, host)
+		}
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func (u *Upgrader) servesHTTPS(ctx context.Context, host string) bool {
+	p := u.probeOf(host)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.done {
+		p.https = u.probe(ctx, host)
+		// a probe interrupted by the context says nothing about the host, so it is probed again next time
+		p.done = ctx.Err() == nil
+	}
+	return p.https
+}
+
+// probeOf returns the probe of the host, creating it if the host is seen first.
+func (u *Upgrader) probeOf(host string) *probe {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	p, ok := u.hosts[host]
+	if !ok {
+		p = &probe{}
+		u.hosts[host] = p
+	}
+	return p
+}
+
+func (p *probe) servesHTTPS() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.https
+}
+
+// probe checks if the host responds over https, any response counts, since only the connection matters.
+func (u *Upgrader) probe(ctx context.Context, host string) bool {
+	target := &url.URL{Scheme: SchemeHTTPS, Host: host, Path: "/"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), http.NoBody)
+	if err != nil {
+		return false
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
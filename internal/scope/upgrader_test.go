@@ -0,0 +1,81 @@
+package scope_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triabokon/goscout/internal/scope"
+	"github.com/triabokon/goscout/internal/scope/mocks"
+)
+
+func TestUpgrader_Upgrade(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewMockHTTPClient(ctrl)
+	// every host is probed once, any response means the host serves https
+	mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, http.MethodHead, req.Method)
+		switch req.URL.String() {
+		case "https://secure.example.com/":
+			return &http.Response{StatusCode: http.StatusMethodNotAllowed, Body: io.NopCloser(strings.NewReader(""))}, nil
+		case "https://legacy.example.com/":
+			return nil, errors.New("connection refused")
+		default:
+			t.Fatalf("unexpected probe %s", req.URL)
+			return nil, nil
+		}
+	}).Times(2)
+
+	upgrader := scope.NewUpgrader(mockClient)
+
+	for u, expected := range map[string]string{
+		"http://secure.example.com/a#top":   "https://secure.example.com/a#top",
+		"http://Secure.example.com:80/b":    "https://secure.example.com/b",
+		"https://secure.example.com/c":      "https://secure.example.com/c",
+		"http://legacy.example.com/a":       "http://legacy.example.com/a",
+		"http://legacy.example.com/b":       "http://legacy.example.com/b",
+		"http://secure.example.com:8080/a":  "http://secure.example.com:8080/a",
+		"https://unknown.example.com/a?b=1": "https://unknown.example.com/a?b=1",
+	} {
+		parsed, pErr := url.Parse(u)
+		require.NoError(t, pErr)
+		assert.Equal(t, expected, upgrader.Upgrade(context.Background(), parsed).String(), u)
+	}
+	assert.Equal(t, []string{"secure.example.com"}, upgrader.UpgradedHosts())
+}
+
+func TestUpgrader_UpgradeCancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockClient := mocks.NewMockHTTPClient(ctrl)
+	gomock.InOrder(
+		mockClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			return nil, req.Context().Err()
+		}),
+		mockClient.EXPECT().Do(gomock.Any()).Return(
+			&http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(""))}, nil,
+		),
+	)
+
+	upgrader := scope.NewUpgrader(mockClient)
+	target, err := url.Parse("http://example.com/a")
+	require.NoError(t, err)
+
+	// the cancelled probe is not kept, so the host is probed again
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, "http://example.com/a", upgrader.Upgrade(ctx, target).String())
+	assert.Equal(t, "https://example.com/a", upgrader.Upgrade(context.Background(), target).String())
+	assert.Equal(t, []string{"example.com"}, upgrader.UpgradedHosts())
+}
@@ -0,0 +1,74 @@
+package seen
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// Bloom keeps a bloom filter of seen urls, it takes a few bits per url no matter how long urls are.
+// A url that was not added could be wrongly reported as seen with the configured false positive rate.
+type Bloom struct {
+	mu   sync.Mutex
+	bits []uint64
+	// m is the number of bits, k is the number of hashes of each url
+	m uint64
+	k uint64
+}
+
+// NewBloom creates the bloom filter sized to keep the false positive rate for the expected number of urls.
+func NewBloom(expectedURLs int, falsePositiveRate float64) (*Bloom, error) {
+	if expectedURLs <= 0 {
+		return nil, fmt.Errorf("expected urls should be positive")
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, fmt.Errorf("false positive rate should be between 0 and 1")
+	}
+	n := float64(expectedURLs)
+	m := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/n*math.Ln2)))
+	return &Bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}, nil
+}
+
+func (b *Bloom) Add(u string) (bool, error) {
+	h1, h2 := hashes(u)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	added := false
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			b.bits[bit/64] |= 1 << (bit % 64)
+			added = true
+		}
+	}
+	return added, nil
+}
+
+func (b *Bloom) Contains(u string) (bool, error) {
+	h1, h2 := hashes(u)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (b *Bloom) Close() error {
+	return nil
+}
+
+// hashes returns two hashes of the url, bits of the url are derived from their combinations.
+func hashes(u string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(u))
+	h2 := fnv.New64()
+	h2.Write([]byte(u))
+	// the second hash is never zero, so bits of the url do not collapse into one
+	return h1.Sum64(), h2.Sum64() | 1
+}
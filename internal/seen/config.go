@@ -0,0 +1,41 @@
+package seen
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+// Storages of the seen set.
+const (
+	// StorageMemory keeps urls in memory.
+	StorageMemory = "memory"
+	// StorageDisk keeps urls in a temporary database on disk.
+	StorageDisk = "disk"
+	// StorageBloom keeps a bloom filter of urls in memory, a few urls are wrongly taken as seen and not crawled.
+	StorageBloom = "bloom"
+)
+
+type Config struct {
+	Storage string
+	// Dir is the directory of the disk storage, the system temporary directory is used if empty
+	Dir string
+	// ExpectedURLs and FalsePositiveRate size the bloom filter
+	ExpectedURLs      int
+	FalsePositiveRate float64
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "SeenConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.StringVar(&c.Storage, "storage", StorageMemory, "storage of crawled urls: memory, disk or bloom")
+	f.StringVar(&c.Dir, "dir", "", "directory of the disk storage, the system temporary directory if empty")
+	f.IntVar(&c.ExpectedURLs, "expected_urls", 10000000, "expected number of crawled urls for the bloom storage")
+	f.Float64Var(
+		&c.FalsePositiveRate, "false_positive_rate",
+		0.001, "share of urls wrongly taken as crawled by the bloom storage, they are not crawled",
+	)
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
@@ -0,0 +1,108 @@
+package seen
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/triabokon/goscout/internal/boltdb"
+	"github.com/triabokon/goscout/internal/crawler"
+)
+
+const bucketSeen = "seen"
+
+// Set is a seen set that should be closed once the crawl is finished.
+type Set interface {
+	crawler.SeenSet
+	io.Closer
+}
+
+// New creates the seen set of the configured storage.
+func New(c Config) (Set, error) {
+	switch c.Storage {
+	case StorageMemory:
+		return NewMemory(), nil
+	case StorageDisk:
+		return NewDisk(c.Dir)
+	case StorageBloom:
+		return NewBloom(c.ExpectedURLs, c.FalsePositiveRate)
+	default:
+		return nil, fmt.Errorf("unknown seen storage %q", c.Storage)
+	}
+}
+
+// Memory keeps seen urls in memory.
+type Memory struct {
+	urls sync.Map
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Add(u string) (bool, error) {
+	_, loaded := m.urls.LoadOrStore(u, struct{}{})
+	return !loaded, nil
+}
+
+func (m *Memory) Contains(u string) (bool, error) {
+	_, ok := m.urls.Load(u)
+	return ok, nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Disk keeps seen urls in a temporary database, it is removed on Close.
+// The set is not synced to disk, since it is rebuilt from the crawl state on resume.
+type Disk struct {
+	db *boltdb.Temp
+}
+
+// NewDisk creates the database in the directory, the system temporary directory is used if it is empty.
+func NewDisk(dir string) (*Disk, error) {
+	db, err := boltdb.OpenTemp(dir, "seen-*.db", bucketSeen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open seen database: %w", err)
+	}
+	return &Disk{db: db}, nil
+}
+
+func (d *Disk) Add(u string) (bool, error) {
+	added := false
+	err := d.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSeen))
+		if b.Get([]byte(u)) != nil {
+			return nil
+		}
+		added = true
+		return b.Put([]byte(u), []byte{})
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to add seen url: %w", err)
+	}
+	return added, nil
+}
+
+func (d *Disk) Contains(u string) (bool, error) {
+	found := false
+	err := d.db.View(func(tx *bbolt.Tx) error {
+		found = tx.Bucket([]byte(bucketSeen)).Get([]byte(u)) != nil
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check seen url: %w", err)
+	}
+	return found, nil
+}
+
+// Close closes and removes the database.
+func (d *Disk) Close() error {
+	if err := d.db.Close(); err != nil {
+		return fmt.Errorf("failed to close seen database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package seen_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triabokon/goscout/internal/seen"
+)
+
+func TestSet_AddContains(t *testing.T) {
+	for _, storage := range []string{seen.StorageMemory, seen.StorageDisk, seen.StorageBloom} {
+		t.Run(storage, func(t *testing.T) {
+			s, err := seen.New(seen.Config{
+				Storage: storage, Dir: t.TempDir(), ExpectedURLs: 1000, FalsePositiveRate: 0.001,
+			})
+			require.NoError(t, err)
+			defer func() { assert.NoError(t, s.Close()) }()
+
+			found, err := s.Contains("https://example.com")
+			require.NoError(t, err)
+			assert.False(t, found)
+
+			added, err := s.Add("https://example.com")
+			require.NoError(t, err)
+			assert.True(t, added)
+
+			added, err = s.Add("https://example.com")
+			require.NoError(t, err)
+			assert.False(t, added)
+
+			found, err = s.Contains("https://example.com")
+			require.NoError(t, err)
+			assert.True(t, found)
+		})
+	}
+}
+
+func TestBloom_FalsePositiveRate(t *testing.T) {
+	const expected = 10000
+	b, err := seen.NewBloom(expected, 0.01)
+	require.NoError(t, err)
+	for i := 0; i < expected; i++ {
+		_, err = b.Add(fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+	}
+	falsePositives := 0
+	for i := 0; i < expected; i++ {
+		found, cErr := b.Contains(fmt.Sprintf("https://example.com/other/%d", i))
+		require.NoError(t, cErr)
+		if found {
+			falsePositives++
+		}
+	}
+	// the rate is allowed to be twice as high to keep the test stable
+	assert.Less(t, falsePositives, expected*2/100)
+}
+
+func TestNew(t *testing.T) {
+	for name, config := range map[string]seen.Config{
+		"unknown storage":  {Storage: "redis"},
+		"no expected urls": {Storage: seen.StorageBloom, FalsePositiveRate: 0.01},
+		"invalid rate":     {Storage: seen.StorageBloom, ExpectedURLs: 1000, FalsePositiveRate: 1},
+		"missing disk dir": {Storage: seen.StorageDisk, Dir: "/nonexistent/dir"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := seen.New(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDisk_Close(t *testing.T) {
+	dir := t.TempDir()
+	d, err := seen.NewDisk(dir)
+	require.NoError(t, err)
+	_, err = d.Add("https://example.com")
+	require.NoError(t, err)
+	assert.NoError(t, d.Close())
+
+	// the database is temporary, so it is removed
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
@@ -0,0 +1,246 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/triabokon/goscout/internal/scope"
+)
+
+var ErrLoggedOut = fmt.Errorf("session is logged out even after logging in again")
+
+// Authentication schemes of host credentials.
+const (
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+)
+
+const (
+	headerAuthorization = "Authorization"
+	headerContentType   = "Content-Type"
+	headerLocation      = "Location"
+
+	formContentType = "application/x-www-form-urlencoded"
+
+	// secrets are read from environment variables or files with these prefixes, other values are used as they are
+	secretEnvPrefix  = "env:"
+	secretFilePrefix = "file:"
+)
+
+// credential is the authorization header sent to the host.
+type credential struct {
+	host          string
+	authorization string
+}
+
+// parseCredential parses "<host> basic <user> <password>" or "<host> bearer <token>",
+// the host could have *. prefix to match its subdomains.
+func parseCredential(value string) (credential, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 2 {
+		return credential{}, fmt.Errorf("credential should have host and scheme")
+	}
+	c := credential{host: strings.ToLower(fields[0])}
+	if err := scope.ValidateHost(c.host); err != nil {
+		return credential{}, err
+	}
+	switch strings.ToLower(fields[1]) {
+	case AuthBasic:
+		if len(fields) != 4 {
+			return credential{}, fmt.Errorf("basic credential should have user and password")
+		}
+		password, err := readSecret(fields[3])
+		if err != nil {
+			return credential{}, fmt.Errorf("failed to read password: %w", err)
+		}
+		req := &http.Request{Header: make(http.Header)}
+		req.SetBasicAuth(fields[2], password)
+		c.authorization = req.Header.Get(headerAuthorization)
+	case AuthBearer:
+		if len(fields) != 3 {
+			return credential{}, fmt.Errorf("bearer credential should have token")
+		}
+		token, err := readSecret(fields[2])
+		if err != nil {
+			return credential{}, fmt.Errorf("failed to read token: %w", err)
+		}
+		c.authorization = "Bearer " + token
+	default:
+		return credential{}, fmt.Errorf("unknown auth scheme %q", fields[1])
+	}
+	return c, nil
+}
+
+// matches checks if the credential is sent to the host, wildcard host does not match the domain itself.
+func (c credential) matches(host string) bool {
+	return scope.MatchHost(c.host, host)
+}
+
+// readSecret reads the value from the environment variable with env: prefix or the file with file: prefix.
+func readSecret(value string) (string, error) {
+	if name, ok := strings.CutPrefix(value, secretEnvPrefix); ok {
+		secret, found := os.LookupEnv(name)
+		if !found {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	}
+	if fileName, ok := strings.CutPrefix(value, secretFilePrefix); ok {
+		secret, err := os.ReadFile(fileName)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+	return value, nil
+}
+
+// isSecretReference checks if the secret is read from the environment variable or the file.
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, secretEnvPrefix) || strings.HasPrefix(value, secretFilePrefix)
+}
+
+// isSecretName checks if the login field or the header, such as password or Authorization, carries a secret.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range []string{"pass", "pwd", "secret", "token", "auth", "cookie", "key", "otp"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// PlainSecrets returns hosts of credentials, and names of login fields and headers with secrets given as they are,
+// rather than read from environment variables or files, so they should not be stored with the config.
+// Login fields and headers are secret by their names, so a plain user name is not a secret.
+func (c *Config) PlainSecrets() []string {
+	var names []string
+	for _, value := range c.Auth {
+		fields := strings.Fields(value)
+		if len(fields) < 3 || isSecretReference(fields[len(fields)-1]) {
+			continue
+		}
+		names = append(names, fields[0])
+	}
+	for _, f := range c.LoginFields {
+		if name, value, _ := strings.Cut(f, "="); isSecretName(name) && !isSecretReference(value) {
+			names = append(names, name)
+		}
+	}
+	for _, h := range c.Headers {
+		name, value, _ := strings.Cut(h, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if isSecretName(name) && !isSecretReference(value) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseLoginForm parses "name=value" fields of the login form, values could be secrets.
+func parseLoginForm(fields []string) (url.Values, error) {
+	form := make(url.Values, len(fields))
+	for _, f := range fields {
+		name, value, ok := strings.Cut(f, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid login field %q", f)
+		}
+		secret, err := readSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read login field %s: %w", name, err)
+		}
+		form.Add(name, secret)
+	}
+	return form, nil
+}
+
+// login keeps the login form and the client it is submitted with, so the session could log in again.
+type login struct {
+	url              *url.URL
+	form             url.Values
+	loggedOutPattern *regexp.Regexp
+	client           HTTPClient
+	// count is the number of successful logins, it tells requests if someone has already logged in again
+	count int
+}
+
+// Login submits the login form with the client, so cookies of the logged in session are stored in the jar.
+// The client should use the session jar and should not follow redirects, it is used to log in again
+// once requests are found logged out. It does nothing if the login url is not set.
+func (s *Session) Login(ctx context.Context, client HTTPClient) error {
+	if s.login == nil {
+		return nil
+	}
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	s.login.client = client
+	return s.submitLogin(ctx)
+}
+
+// relogin logs in again unless someone has already done it since the request was sent.
+func (s *Session) relogin(ctx context.Context, count int) error {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	if s.login.count != count {
+		return nil
+	}
+	return s.submitLogin(ctx)
+}
+
+func (s *Session) submitLogin(ctx context.Context) error {
+	if s.login.client == nil {
+		return fmt.Errorf("session has not logged in")
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, s.login.url.String(), strings.NewReader(s.login.form.Encode()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create login request: %w", err)
+	}
+	req = s.decorate(req)
+	req.Header.Set(headerContentType, formContentType)
+	resp, err := s.login.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send login request: %w", err)
+	}
+	defer resp.Body.Close()
+	// the login form usually redirects back to itself if credentials are wrong
+	if resp.StatusCode >= http.StatusBadRequest || s.loggedOut(req, resp) {
+		return fmt.Errorf("login failed with status %d", resp.StatusCode)
+	}
+	s.login.count++
+	return nil
+}
+
+// loginCount returns the number of successful logins.
+func (s *Session) loginCount() int {
+	s.loginMu.Lock()
+	defer s.loginMu.Unlock()
+	return s.login.count
+}
+
+// loggedOut checks if the response asks to log in: its status is 401 Unauthorized
+// or it redirects to the login page or to a url matching the logged out pattern.
+func (s *Session) loggedOut(req *http.Request, resp *http.Response) bool {
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	location := resp.Header.Get(headerLocation)
+	if location == "" || resp.StatusCode < http.StatusMultipleChoices || resp.StatusCode >= http.StatusBadRequest {
+		return false
+	}
+	target, err := req.URL.Parse(location)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(target.Host, s.login.url.Host) && target.Path == s.login.url.Path {
+		return true
+	}
+	return s.login.loggedOutPattern != nil && s.login.loggedOutPattern.MatchString(target.String())
+}
@@ -0,0 +1,211 @@
+package session_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triabokon/goscout/internal/session"
+	"github.com/triabokon/goscout/internal/session/mocks"
+)
+
+func TestClient_DoAuth(t *testing.T) {
+	t.Setenv("GOSCOUT_TEST_PASSWORD", "secret")
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("abc123\n"), 0o600))
+
+	s, err := session.New(session.Config{Auth: []string{
+		"staging.example.com basic user env:GOSCOUT_TEST_PASSWORD",
+		"*.api.example.com bearer file:" + tokenFile,
+		"Docs.example.com Bearer plain",
+	}})
+	require.NoError(t, err)
+
+	for u, expected := range map[string]string{
+		"https://staging.example.com/page":  "Basic dXNlcjpzZWNyZXQ=",
+		"https://v1.api.example.com/users":  "Bearer abc123",
+		"https://a.b.api.example.com/users": "Bearer abc123",
+		"https://docs.example.com:8080/":    "Bearer plain",
+		// wildcard does not match the domain itself
+		"https://api.example.com/users": "",
+		"https://example.com/":          "",
+	} {
+		t.Run(u, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			client := mocks.NewMockHTTPClient(ctrl)
+			client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+				assert.Equal(t, expected, req.Header.Get("Authorization"))
+				return &http.Response{StatusCode: http.StatusOK}, nil
+			})
+			_, dErr := s.Client(client).Get(u)
+			require.NoError(t, dErr)
+		})
+	}
+
+	t.Run("authorization set by the caller is kept", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		client := mocks.NewMockHTTPClient(ctrl)
+		client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+			assert.Equal(t, "Bearer own", req.Header.Get("Authorization"))
+			return &http.Response{StatusCode: http.StatusOK}, nil
+		})
+		req, rErr := http.NewRequest(http.MethodGet, "https://staging.example.com", http.NoBody)
+		require.NoError(t, rErr)
+		req.Header.Set("Authorization", "Bearer own")
+		_, dErr := s.Client(client).Do(req)
+		require.NoError(t, dErr)
+	})
+}
+
+func TestNew_Auth(t *testing.T) {
+	for name, config := range map[string]session.Config{
+		"missing scheme":          {Auth: []string{"example.com"}},
+		"unknown scheme":          {Auth: []string{"example.com digest user password"}},
+		"basic without password":  {Auth: []string{"example.com basic user"}},
+		"bearer without token":    {Auth: []string{"example.com bearer"}},
+		"invalid wildcard":        {Auth: []string{"api.*.example.com bearer token"}},
+		"unset environment":       {Auth: []string{"example.com bearer env:GOSCOUT_TEST_UNSET"}},
+		"missing secret file":     {Auth: []string{"example.com bearer file:/nonexistent/token"}},
+		"relative login url":      {LoginURL: "/login"},
+		"login field without '='": {LoginURL: "https://example.com/login", LoginFields: []string{"user"}},
+		"invalid logged out pattern": {
+			LoginURL: "https://example.com/login", LoggedOutPattern: "(",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := session.New(config)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConfig_PlainSecrets(t *testing.T) {
+	c := session.Config{
+		Auth: []string{
+			"staging.example.com basic user env:GOSCOUT_TEST_PASSWORD",
+			"*.api.example.com bearer file:/run/secrets/token",
+			"docs.example.com bearer plain",
+		},
+		// only fields and headers named as secrets are secret
+		LoginFields: []string{"user=alice", "password=secret", "otp=env:GOSCOUT_TEST_OTP"},
+		Headers: []string{
+			"Accept-Language: de-DE", "Authorization: Bearer token", "Cookie: session=1", "X-Api-Key: file:key.txt",
+		},
+	}
+	assert.Equal(t, []string{"docs.example.com", "password", "Authorization", "Cookie"}, c.PlainSecrets())
+	assert.Empty(t, (&session.Config{}).PlainSecrets())
+}
+
+// loginServer serves pages to clients with the session cookie, other clients are redirected to the login form.
+type loginServer struct {
+	*httptest.Server
+	logins atomic.Int32
+}
+
+func newLoginServer(t *testing.T) *loginServer {
+	s := &loginServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			if r.Method != http.MethodPost || r.FormValue("user") != "admin" || r.FormValue("password") != "secret" {
+				http.Redirect(w, r, "/login?failed=1", http.StatusFound)
+				return
+			}
+			s.logins.Add(1)
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "valid", Path: "/"})
+			http.Redirect(w, r, "/", http.StatusFound)
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "expired", Path: "/"})
+			w.Write([]byte("bye"))
+		case "/api":
+			if c, err := r.Cookie("session"); err != nil || c.Value != "valid" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("api"))
+		default:
+			if c, err := r.Cookie("session"); err != nil || c.Value != "valid" {
+				http.Redirect(w, r, "/login?next="+r.URL.Path, http.StatusFound)
+				return
+			}
+			w.Write([]byte("page"))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestSession_Login(t *testing.T) {
+	t.Setenv("GOSCOUT_TEST_PASSWORD", "secret")
+	server := newLoginServer(t)
+
+	newSession := func(t *testing.T, fields ...string) *session.Session {
+		s, err := session.New(session.Config{LoginURL: server.URL + "/login", LoginFields: fields})
+		require.NoError(t, err)
+		noRedirect := func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		err = s.Login(context.Background(), &http.Client{Jar: s.Jar(), CheckRedirect: noRedirect})
+		if err == nil {
+			return s
+		}
+		assert.ErrorContains(t, err, "login failed")
+		return nil
+	}
+	get := func(t *testing.T, client *session.Client, u string) string {
+		resp, err := client.Get(u)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	t.Run("wrong credentials", func(t *testing.T) {
+		assert.Nil(t, newSession(t, "user=admin", "password=wrong"))
+	})
+
+	t.Run("session cookies are carried through the crawl", func(t *testing.T) {
+		s := newSession(t, "user=admin", "password=env:GOSCOUT_TEST_PASSWORD")
+		require.NotNil(t, s)
+		client := s.Client(&http.Client{Jar: s.Jar()})
+		assert.Equal(t, "page", get(t, client, server.URL+"/a"))
+		assert.Equal(t, "api", get(t, client, server.URL+"/api"))
+	})
+
+	t.Run("logs in again once logged out", func(t *testing.T) {
+		s := newSession(t, "user=admin", "password=secret")
+		require.NotNil(t, s)
+		logins := server.logins.Load()
+		client := s.Client(&http.Client{Jar: s.Jar()})
+		assert.Equal(t, "bye", get(t, client, server.URL+"/logout"))
+		// 401 status shows that the session is logged out
+		assert.Equal(t, "api", get(t, client, server.URL+"/api"))
+		assert.Equal(t, logins+1, server.logins.Load())
+
+		noRedirect := func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+		client = s.Client(&http.Client{Jar: s.Jar(), CheckRedirect: noRedirect})
+		assert.Equal(t, "bye", get(t, client, server.URL+"/logout"))
+		assert.Equal(t, "page", get(t, client, server.URL+"/b"))
+		assert.Equal(t, logins+2, server.logins.Load())
+	})
+
+	t.Run("logged out after logging in again", func(t *testing.T) {
+		s := newSession(t, "user=admin", "password=secret")
+		require.NotNil(t, s)
+		// the client without the jar never keeps the session
+		_, err := s.Client(&http.Client{}).Get(server.URL + "/api")
+		assert.ErrorIs(t, err, session.ErrLoggedOut)
+	})
+}
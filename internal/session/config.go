@@ -0,0 +1,64 @@
+package session
+
+import (
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+// DefaultUserAgent identifies goscout, since the default user agent of Go is blocked by some CDNs.
+const DefaultUserAgent = "Mozilla/5.0 (compatible; goscout/1.0; +https://github.com/triabokon/goscout)"
+
+type Config struct {
+	UserAgent string
+	// Headers are "Name: value" pairs added to every request, values could be secrets as well
+	Headers []string
+	// Cookies enables the cookie jar, it is enabled anyway if the cookies file is set
+	Cookies bool
+	// CookiesFile is the Netscape cookies.txt file the cookie jar is seeded from
+	CookiesFile string
+
+	// Auth are "<host> basic <user> <password>" or "<host> bearer <token>" credentials,
+	// secrets could be read from environment variables or files with env: and file: prefixes
+	Auth []string
+	// LoginURL is the url the login form is submitted to before the crawl, empty disables login
+	LoginURL string
+	// LoginFields are "name=value" fields of the login form, values could be secrets as well
+	LoginFields []string
+	// LoggedOutPattern is the regexp of redirect urls that show the session is logged out
+	LoggedOutPattern string
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "SessionConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.StringVar(&c.UserAgent, "user_agent", DefaultUserAgent, "user agent of requests")
+	f.StringArrayVar(
+		&c.Headers, "header", nil,
+		`"Name: value" header added to every request, such as "Accept-Language: de-DE", `+
+			"value could be read with env:NAME and file:PATH",
+	)
+	f.BoolVar(&c.Cookies, "cookies", false, "keep cookies set by the site and send them back")
+	f.StringVar(
+		&c.CookiesFile, "cookies_file",
+		"", "Netscape cookies.txt file to load cookies from, it enables cookies",
+	)
+
+	f.StringArrayVar(
+		&c.Auth, "auth", nil,
+		`"<host> basic <user> <password>" or "<host> bearer <token>" credentials, host could be *.example.com, `+
+			"secrets could be read with env:NAME and file:PATH",
+	)
+	f.StringVar(&c.LoginURL, "login_url", "", "url to submit the login form to before the crawl, it enables cookies")
+	f.StringArrayVar(
+		&c.LoginFields, "login_field", nil,
+		`"name=value" field of the login form, value could be read with env:NAME and file:PATH`,
+	)
+	f.StringVar(
+		&c.LoggedOutPattern, "logged_out_pattern",
+		"", "regexp of redirect urls that show the session is logged out, besides 401 status and the login url",
+	)
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
@@ -0,0 +1,82 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpOnlyPrefix marks http-only cookies in cookies.txt files written by browsers and curl.
+const httpOnlyPrefix = "#HttpOnly_"
+
+// fileCookie is a cookie from the cookies file with the url it is set for.
+type fileCookie struct {
+	url    *url.URL
+	cookie *http.Cookie
+}
+
+// loadCookies adds cookies from the Netscape cookies.txt file to the jar, expired cookies are ignored by the jar.
+func loadCookies(jar http.CookieJar, fileName string) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to open cookies file: %w", err)
+	}
+	defer f.Close()
+	cookies, err := parseCookies(f)
+	if err != nil {
+		return fmt.Errorf("failed to parse cookies file: %w", err)
+	}
+	for _, c := range cookies {
+		jar.SetCookies(c.url, []*http.Cookie{c.cookie})
+	}
+	return nil
+}
+
+// parseCookies parses lines of tab separated domain, subdomains flag, path, secure flag,
+// expiration time in unix seconds, name and value.
+func parseCookies(r io.Reader) ([]fileCookie, error) {
+	var cookies []fileCookie
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(text, httpOnlyPrefix)
+		text = strings.TrimPrefix(text, httpOnlyPrefix)
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d should have 7 tab separated fields", line)
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d has invalid expiration time: %w", line, err)
+		}
+		host := strings.TrimPrefix(fields[0], ".")
+		secure := strings.EqualFold(fields[3], "TRUE")
+		cookie := &http.Cookie{Name: fields[5], Value: fields[6], Path: fields[2], Secure: secure, HttpOnly: httpOnly}
+		// the cookie of a domain is sent to its subdomains, otherwise it is sent to the host only
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = host
+		}
+		// zero expiration time is used for session cookies
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+		scheme := "http"
+		if secure {
+			scheme = "https"
+		}
+		cookies = append(cookies, fileCookie{url: &url.URL{Scheme: scheme, Host: host, Path: fields[2]}, cookie: cookie})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %w", err)
+	}
+	return cookies, nil
+}
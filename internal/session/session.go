@@ -0,0 +1,184 @@
+package session
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/http/httpguts"
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/triabokon/goscout/internal/retry"
+)
+
+const headerUserAgent = "User-Agent"
+
+//go:generate mockgen -destination=./mocks/http_mock.go -package=mocks github.com/triabokon/goscout/internal/session HTTPClient
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Session contains headers, credentials and cookies shared by all requests of the crawl.
+type Session struct {
+	config      Config
+	headers     http.Header
+	credentials []credential
+	jar         http.CookieJar
+	// login is set if the session logs in with the form, loginMu guards its client and count
+	login   *login
+	loginMu sync.Mutex
+}
+
+// New parses configured headers and credentials, and creates the cookie jar, seeding it from the cookies file.
+// Cookies are enabled if the session logs in with the form, so they carry the logged in session.
+func New(c Config) (*Session, error) {
+	headers, err := parseHeaders(c.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse headers: %w", err)
+	}
+	s := &Session{config: c, headers: headers}
+	for _, value := range c.Auth {
+		cred, cErr := parseCredential(value)
+		if cErr != nil {
+			return nil, fmt.Errorf("failed to parse credential: %w", cErr)
+		}
+		s.credentials = append(s.credentials, cred)
+	}
+	if c.LoginURL != "" {
+		if s.login, err = newLogin(c); err != nil {
+			return nil, err
+		}
+	}
+	if !c.Cookies && c.CookiesFile == "" && s.login == nil {
+		return s, nil
+	}
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	if c.CookiesFile != "" {
+		if err = loadCookies(jar, c.CookiesFile); err != nil {
+			return nil, fmt.Errorf("failed to load cookies: %w", err)
+		}
+	}
+	s.jar = jar
+	return s, nil
+}
+
+// Jar returns the cookie jar for http clients, it is nil if cookies are disabled.
+func (s *Session) Jar() http.CookieJar {
+	return s.jar
+}
+
+// Client wraps the client, so its requests are sent with the session headers.
+func (s *Session) Client(client HTTPClient) *Client {
+	return &Client{session: s, client: client}
+}
+
+// Client is an http client that adds the user agent, headers and credentials of the session to requests,
+// headers that are set by the caller are kept. If the response shows that the session is logged out,
+// it logs in again and resends the request once.
+type Client struct {
+	session *Session
+	client  HTTPClient
+}
+
+// Do sends the copy of the request with the session headers.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c.session.login == nil {
+		return c.client.Do(c.session.decorate(req))
+	}
+	count := c.session.loginCount()
+	resp, err := c.client.Do(c.session.decorate(req))
+	if err != nil || !c.session.loggedOut(req, resp) {
+		return resp, err
+	}
+	retry.Drain(resp.Body)
+	if err = c.session.relogin(req.Context(), count); err != nil {
+		return nil, fmt.Errorf("failed to log in again: %w", err)
+	}
+	resp, err = c.client.Do(c.session.decorate(req))
+	if err != nil {
+		return nil, err
+	}
+	if c.session.loggedOut(req, resp) {
+		retry.Drain(resp.Body)
+		return nil, ErrLoggedOut
+	}
+	return resp, nil
+}
+
+// Get sends GET request to the url.
+func (c *Client) Get(u string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	return c.Do(req)
+}
+
+// decorate returns the copy of the request with the session headers and the credential of its host.
+func (s *Session) decorate(req *http.Request) *http.Request {
+	req = req.Clone(req.Context())
+	if s.config.UserAgent != "" && req.Header.Get(headerUserAgent) == "" {
+		req.Header.Set(headerUserAgent, s.config.UserAgent)
+	}
+	for name, values := range s.headers {
+		if _, ok := req.Header[name]; !ok {
+			req.Header[name] = values
+		}
+	}
+	if req.Header.Get(headerAuthorization) == "" {
+		for _, c := range s.credentials {
+			if c.matches(req.URL.Hostname()) {
+				req.Header.Set(headerAuthorization, c.authorization)
+				break
+			}
+		}
+	}
+	return req
+}
+
+func newLogin(c Config) (*login, error) {
+	loginURL, err := url.Parse(c.LoginURL)
+	if err != nil || !loginURL.IsAbs() {
+		return nil, fmt.Errorf("invalid login url %q", c.LoginURL)
+	}
+	form, err := parseLoginForm(c.LoginFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse login form: %w", err)
+	}
+	l := &login{url: loginURL, form: form}
+	if c.LoggedOutPattern != "" {
+		if l.loggedOutPattern, err = regexp.Compile(c.LoggedOutPattern); err != nil {
+			return nil, fmt.Errorf("failed to compile logged out pattern: %w", err)
+		}
+	}
+	return l, nil
+}
+
+// parseHeaders parses "Name: value" pairs, values of the same name are all sent.
+// Values could be secrets read from environment variables or files.
+func parseHeaders(pairs []string) (http.Header, error) {
+	headers := make(http.Header, len(pairs))
+	for _, p := range pairs {
+		name, value, ok := strings.Cut(p, ":")
+		name = strings.TrimSpace(name)
+		if !ok || !httpguts.ValidHeaderFieldName(name) {
+			return nil, fmt.Errorf("invalid header %q", p)
+		}
+		value, err := readSecret(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header %s: %w", name, err)
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid value of header %s", name)
+		}
+		headers.Add(name, value)
+	}
+	return headers, nil
+}
@@ -0,0 +1,121 @@
+package session_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triabokon/goscout/internal/session"
+	"github.com/triabokon/goscout/internal/session/mocks"
+)
+
+func TestClient_Do(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	t.Setenv("GOSCOUT_TEST_TOKEN", "other")
+	s, err := session.New(session.Config{
+		UserAgent: "goscout-test",
+		Headers: []string{
+			"Accept-Language: de-DE", "X-Token:  secret ", "Accept: text/html", "X-Token: env:GOSCOUT_TEST_TOKEN",
+		},
+	})
+	require.NoError(t, err)
+
+	client := mocks.NewMockHTTPClient(ctrl)
+	client.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		assert.Equal(t, "goscout-test", req.Header.Get("User-Agent"))
+		assert.Equal(t, "de-DE", req.Header.Get("Accept-Language"))
+		assert.Equal(t, []string{"secret", "other"}, req.Header.Values("X-Token"))
+		// headers set by the caller are kept
+		assert.Equal(t, "application/xhtml+xml", req.Header.Get("Accept"))
+		return &http.Response{StatusCode: http.StatusOK}, nil
+	})
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com", http.NoBody)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/xhtml+xml")
+	resp, err := s.Client(client).Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	// the request of the caller is not changed
+	assert.Empty(t, req.Header.Get("User-Agent"))
+}
+
+func TestNew(t *testing.T) {
+	for name, config := range map[string]session.Config{
+		"header without value separator": {Headers: []string{"Accept-Language de-DE"}},
+		"invalid header name":            {Headers: []string{"Accept Language: de-DE"}},
+		"missing header secret":          {Headers: []string{"Authorization: env:GOSCOUT_TEST_MISSING"}},
+		"missing cookies file":           {CookiesFile: "/nonexistent/cookies.txt"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := session.New(config)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("cookies are disabled", func(t *testing.T) {
+		s, err := session.New(session.Config{})
+		require.NoError(t, err)
+		assert.Nil(t, s.Jar())
+	})
+}
+
+func TestSession_Cookies(t *testing.T) {
+	cookiesFile := filepath.Join(t.TempDir(), "cookies.txt")
+	require.NoError(t, os.WriteFile(cookiesFile, []byte(
+		"# Netscape HTTP Cookie File\n"+
+			"\n"+
+			".example.com\tTRUE\t/\tFALSE\t0\tlang\tde\n"+
+			"#HttpOnly_shop.example.com\tFALSE\t/cart\tTRUE\t4102444800\tsession\tabc\n"+
+			"example.com\tFALSE\t/\tFALSE\t946684800\texpired\tyes\n",
+	), 0o600))
+
+	s, err := session.New(session.Config{CookiesFile: cookiesFile})
+	require.NoError(t, err)
+
+	cookies := func(u string) map[string]string {
+		parsed, pErr := url.Parse(u)
+		require.NoError(t, pErr)
+		values := make(map[string]string)
+		for _, c := range s.Jar().Cookies(parsed) {
+			values[c.Name] = c.Value
+		}
+		return values
+	}
+	assert.Equal(t, map[string]string{"lang": "de"}, cookies("http://example.com/"))
+	assert.Equal(t, map[string]string{"lang": "de"}, cookies("http://news.example.com/"))
+	assert.Equal(t, map[string]string{"lang": "de", "session": "abc"}, cookies("https://shop.example.com/cart/1"))
+	// the secure cookie is not sent over http
+	assert.Equal(t, map[string]string{"lang": "de"}, cookies("http://shop.example.com/cart/1"))
+
+	t.Run("cookies set by the site are sent back", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c, cErr := r.Cookie("visited"); cErr == nil {
+				w.Write([]byte(c.Value))
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "visited", Value: "yes"})
+		}))
+		defer server.Close()
+
+		client := s.Client(&http.Client{Jar: s.Jar()})
+		for _, expected := range []string{"", "yes"} {
+			resp, gErr := client.Get(server.URL)
+			require.NoError(t, gErr)
+			body, rErr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			require.NoError(t, rErr)
+			assert.Equal(t, expected, string(body))
+		}
+	})
+}
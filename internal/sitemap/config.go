@@ -6,20 +6,73 @@ import (
 	"github.com/triabokon/goscout/flags"
 )
 
+const (
+	// FormatStandard is a flat sitemap following sitemaps.org protocol.
+	FormatStandard = "standard"
+	// FormatTree is a sitemap with urls nested in each other as they were discovered.
+	FormatTree = "tree"
+)
+
+// Sitemap protocol limits for a single file.
+const (
+	MaxURLsPerFile = 50000
+	MaxFileSize    = 50 << 20
+)
+
 type Config struct {
+	Format string
 	XMLNS  string
 	Indent int
+
+	MaxURLs     int
+	MaxFileSize int
+	BaseURL     string
+	Gzip        bool
+
+	ChangeFreq    string
+	DepthPriority bool
+	Rules         []string
+
+	IncludeErrorPages bool
 }
 
 func (c *Config) Flags(prefix string) *pflag.FlagSet {
 	const name = "SitemapConfig"
 	f := pflag.NewFlagSet(name, pflag.PanicOnError)
 
+	f.StringVar(&c.Format, "format", FormatStandard, "sitemap format: standard or tree")
 	f.StringVar(
 		&c.XMLNS, "xml_ns",
-		"https://www.sitemaps.org/schemas/sitemap/0.9/", "xml sitemap namespace",
+		"http://www.sitemaps.org/schemas/sitemap/0.9", "xml sitemap namespace",
 	)
 	f.IntVar(&c.Indent, "indent", 1, "xml sitemap indent")
+	f.IntVar(
+		&c.MaxURLs, "max_urls",
+		MaxURLsPerFile, "maximum number of urls in a sitemap file, larger sitemap is split into parts with an index",
+	)
+	f.IntVar(
+		&c.MaxFileSize, "max_file_size",
+		MaxFileSize, "maximum uncompressed size of a sitemap file in bytes, larger sitemap is split into parts with an index",
+	)
+	f.StringVar(
+		&c.BaseURL, "base_url",
+		"", "url where sitemap parts are published, used in the sitemap index (default site url root)",
+	)
+	f.BoolVar(&c.Gzip, "gzip", false, "gzip sitemap files")
+	f.StringVar(&c.ChangeFreq, "changefreq", "", "default change frequency of urls, omitted if empty")
+	f.BoolVar(
+		&c.DepthPriority, "depth_priority",
+		true, "derive url priority from its crawl depth, from 1.0 for the site url down to 0.1",
+	)
+	f.StringArrayVar(
+		&c.Rules, "rule",
+		nil, "rule \"<url regexp> <changefreq> <priority>\" to set change frequency and priority of matching urls, "+
+			"use - to keep the default value, the first matching rule is applied",
+	)
+	f.BoolVar(
+		&c.IncludeErrorPages, "include_error_pages",
+		false, "include pages fetched with non-2xx status in the standard sitemap",
+	)
 
 	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
 }
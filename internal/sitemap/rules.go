@@ -0,0 +1,83 @@
+package sitemap
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	ChangeFreqAlways  = "always"
+	ChangeFreqHourly  = "hourly"
+	ChangeFreqDaily   = "daily"
+	ChangeFreqWeekly  = "weekly"
+	ChangeFreqMonthly = "monthly"
+	ChangeFreqYearly  = "yearly"
+	ChangeFreqNever   = "never"
+)
+
+const (
+	// ruleKeep is used in a rule to keep the default value.
+	ruleKeep = "-"
+
+	maxPriority       = 1.0
+	minPriority       = 0.1
+	depthPriorityStep = 0.1
+)
+
+// rule sets change frequency and priority of urls matching the pattern, empty values are not changed.
+type rule struct {
+	pattern    *regexp.Regexp
+	changeFreq string
+	priority   string
+}
+
+// parseRule parses rule in the "<url regexp> <changefreq> <priority>" format.
+func parseRule(value string) (*rule, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("rule %q should have url regexp, change frequency and priority", value)
+	}
+	pattern, err := regexp.Compile(fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile rule regexp: %w", err)
+	}
+	r := &rule{pattern: pattern}
+	if fields[1] != ruleKeep {
+		if !validChangeFreq(fields[1]) {
+			return nil, fmt.Errorf("rule %q has invalid change frequency", value)
+		}
+		r.changeFreq = fields[1]
+	}
+	if fields[2] != ruleKeep {
+		priority, pErr := strconv.ParseFloat(fields[2], 64)
+		if pErr != nil || priority < 0 || priority > maxPriority {
+			return nil, fmt.Errorf("rule %q should have priority between 0.0 and 1.0", value)
+		}
+		r.priority = formatPriority(priority)
+	}
+	return r, nil
+}
+
+func validChangeFreq(changeFreq string) bool {
+	switch changeFreq {
+	case ChangeFreqAlways, ChangeFreqHourly, ChangeFreqDaily, ChangeFreqWeekly,
+		ChangeFreqMonthly, ChangeFreqYearly, ChangeFreqNever:
+		return true
+	}
+	return false
+}
+
+// depthPriority returns priority that decreases with depth, the site url at depth 1 has the maximum priority.
+func depthPriority(depth int) string {
+	priority := maxPriority - depthPriorityStep*float64(depth-1)
+	if priority < minPriority {
+		priority = minPriority
+	}
+	return formatPriority(priority)
+}
+
+func formatPriority(priority float64) string {
+	return strconv.FormatFloat(priority, 'f', 1, 64)
+}
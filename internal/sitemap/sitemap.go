@@ -3,18 +3,37 @@ package sitemap
 import (
 	"encoding/xml"
 	"fmt"
-	"os"
-	"strings"
+	"net/http"
+	"sort"
+	"time"
 )
 
-const indentSymbol = " "
-
 type SiteMap struct {
 	config Config
-	index  *Index
+	rules  []*rule
+	// pages are listed in the standard format, their entries are generated while the sitemap is written
+	pages Pages
+	tree  *Tree
+	// partial marks the sitemap generated from an interrupted crawl
+	partial bool
+}
+
+// URLSet is a flat sitemap following sitemaps.org 0.9 schema.
+type URLSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	URLs    []*Entry `xml:"url"`
+}
+
+type Entry struct {
+	Loc        string `xml:"loc"`
+	LastMod    string `xml:"lastmod,omitempty"`
+	ChangeFreq string `xml:"changefreq,omitempty"`
+	Priority   string `xml:"priority,omitempty"`
 }
 
-type Index struct {
+// Tree is a sitemap that mirrors the discovery tree, so urls are nested in each other.
+type Tree struct {
 	XMLName xml.Name `xml:"urlset"`
 	XMLNS   string   `xml:"xmlns,attr"`
 	URL     *URL     `xml:"url"`
@@ -25,53 +44,169 @@ type URL struct {
 	URLs []*URL `xml:"url"`
 }
 
-func New(config Config) *SiteMap {
-	return &SiteMap{
-		config: config,
-		index: &Index{
-			XMLNS: config.XMLNS,
-		},
+// Pages are crawled pages to put in the sitemap, Range calls f for every page once by its url,
+// in the order the pages are listed, the standard format lists them ordered by depth.
+type Pages interface {
+	Range(f func(loc string, page Page) error) error
+}
+
+// PageMap is a set of pages by their urls kept in memory, it is ranged ordered by depth and url.
+type PageMap map[string]Page
+
+func (m PageMap) Range(f func(loc string, page Page) error) error {
+	locs := make([]string, 0, len(m))
+	for loc := range m {
+		locs = append(locs, loc)
 	}
+	sort.Slice(locs, func(i, j int) bool {
+		if m[locs[i]].Depth != m[locs[j]].Depth {
+			return m[locs[i]].Depth < m[locs[j]].Depth
+		}
+		return locs[i] < locs[j]
+	})
+	for _, loc := range locs {
+		if err := f(loc, m[loc]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (s *SiteMap) GenerateSitemap(data map[string][]string, rootValue string) {
-	s.index.URL = generateSitemap(data, rootValue)
+// Page is a crawled web page to put in the sitemap.
+type Page struct {
+	// URLs found on the page, they are used by the tree format
+	URLs         []string
+	Depth        int
+	LastModified time.Time
+	// Status is the response status code of the page, zero if unknown
+	Status int
+	// Canonical is the canonical url of the page, the page is not listed if it points to another url
+	Canonical string
+	// NoIndex is set if the page asks not to be indexed, such page is not listed
+	NoIndex bool
 }
 
-func (s *SiteMap) Index() *Index {
-	return s.index
+func New(config Config) (*SiteMap, error) {
+	if config.Format != FormatStandard && config.Format != FormatTree {
+		return nil, fmt.Errorf("unknown sitemap format %q", config.Format)
+	}
+	if config.MaxURLs <= 0 || config.MaxURLs > MaxURLsPerFile {
+		return nil, fmt.Errorf("max urls should be between 1 and %d", MaxURLsPerFile)
+	}
+	if config.MaxFileSize <= 0 || config.MaxFileSize > MaxFileSize {
+		return nil, fmt.Errorf("max file size should be between 1 and %d bytes", MaxFileSize)
+	}
+	if config.ChangeFreq != "" && !validChangeFreq(config.ChangeFreq) {
+		return nil, fmt.Errorf("unknown change frequency %q", config.ChangeFreq)
+	}
+	rules := make([]*rule, 0, len(config.Rules))
+	for _, value := range config.Rules {
+		r, err := parseRule(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	return &SiteMap{config: config, rules: rules}, nil
 }
 
-// WriteToFile writes the xml site map to a file with filename.
-func (s *SiteMap) WriteToFile(filename string) error {
-	file, err := os.Create(filename)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+// GenerateSitemap generates sitemap of the configured format from crawled pages.
+// The tree is built in memory, while pages of the standard format are ranged once the sitemap is written,
+// so they could be read from a storage on disk.
+func (s *SiteMap) GenerateSitemap(pages Pages, rootValue string) error {
+	if s.config.Format != FormatTree {
+		s.pages = pages
+		return nil
 	}
-	if _, err = file.WriteString(xml.Header); err != nil {
-		return fmt.Errorf("failed to write xml header to file: %w", err)
+	tree := make(map[string]Page)
+	err := pages.Range(func(loc string, page Page) error {
+		tree[loc] = page
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read pages: %w", err)
 	}
-	xmlSitemap, err := xml.MarshalIndent(s.index, "", strings.Repeat(indentSymbol, s.config.Indent))
+	s.tree = &Tree{XMLNS: s.config.XMLNS, URL: generateTree(tree, rootValue)}
+	return nil
+}
+
+// URLSet returns the sitemap generated in the standard format, all its entries are kept in memory,
+// so large sitemaps should be written with WriteToFile.
+func (s *SiteMap) URLSet() (*URLSet, error) {
+	urlSet := &URLSet{XMLNS: s.config.XMLNS}
+	err := s.rangeEntries(func(e *Entry) error {
+		urlSet.URLs = append(urlSet.URLs, e)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to marshal sitemap: %w", err)
+		return nil, err
 	}
-	if _, err = file.Write(xmlSitemap); err != nil {
-		return fmt.Errorf("failed to write sitemap to file: %w", err)
+	return urlSet, nil
+}
+
+// Tree returns the sitemap generated in the tree format.
+func (s *SiteMap) Tree() *Tree {
+	return s.tree
+}
+
+// MarkPartial marks the sitemap as generated from an interrupted crawl, it is noted in the written file.
+func (s *SiteMap) MarkPartial() {
+	s.partial = true
+}
+
+// rangeEntries calls f for sitemap entries of pages in their order,
+// pages with errors, noindex pages and pages pointing to another canonical url are omitted.
+func (s *SiteMap) rangeEntries(f func(e *Entry) error) error {
+	if s.pages == nil {
+		return nil
 	}
-	if cErr := file.Close(); cErr != nil {
-		return fmt.Errorf("failed to close file: %w", err)
+	err := s.pages.Range(func(loc string, page Page) error {
+		if !s.config.IncludeErrorPages && isErrorStatus(page.Status) {
+			return nil
+		}
+		if page.NoIndex || (page.Canonical != "" && page.Canonical != loc) {
+			return nil
+		}
+		return f(s.entry(loc, page))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read pages: %w", err)
 	}
 	return nil
 }
 
+// entry creates sitemap entry for the page, applying the first rule that matches its url.
+func (s *SiteMap) entry(loc string, page Page) *Entry {
+	e := &Entry{Loc: loc, ChangeFreq: s.config.ChangeFreq}
+	if !page.LastModified.IsZero() {
+		e.LastMod = page.LastModified.UTC().Format(time.RFC3339)
+	}
+	if s.config.DepthPriority {
+		e.Priority = depthPriority(page.Depth)
+	}
+	for _, r := range s.rules {
+		if !r.pattern.MatchString(loc) {
+			continue
+		}
+		if r.changeFreq != "" {
+			e.ChangeFreq = r.changeFreq
+		}
+		if r.priority != "" {
+			e.Priority = r.priority
+		}
+		break
+	}
+	return e
+}
+
 type stackItem struct {
 	value string
 	node  *URL
 }
 
-// generateSitemap builds sitemap as a tree structure from given map.
-func generateSitemap(data map[string][]string, rootValue string) *URL {
-	visited := make(map[string]bool, len(data))
+// generateTree builds sitemap as a tree structure from given pages.
+func generateTree(pages map[string]Page, rootValue string) *URL {
+	visited := make(map[string]bool, len(pages))
 	rootNode := &URL{Loc: rootValue}
 	// stack is used to process the urls in a depth-first search manner
 	stack := []*stackItem{{value: rootValue, node: rootNode}}
@@ -85,8 +220,8 @@ func generateSitemap(data map[string][]string, rootValue string) *URL {
 			continue
 		}
 		// if the url has children, add them to the node and stack
-		if children, ok := data[item.value]; ok {
-			for _, childValue := range children {
+		if page, ok := pages[item.value]; ok {
+			for _, childValue := range page.URLs {
 				child := &URL{Loc: childValue}
 				item.node.URLs = append(item.node.URLs, child)
 				stack = append(stack, &stackItem{value: childValue, node: child})
@@ -96,3 +231,8 @@ func generateSitemap(data map[string][]string, rootValue string) *URL {
 	}
 	return rootNode
 }
+
+// isErrorStatus checks if the page was fetched with non-2xx status, unknown status is not an error.
+func isErrorStatus(status int) bool {
+	return status != 0 && (status < http.StatusOK || status >= http.StatusMultipleChoices)
+}
@@ -1,37 +1,322 @@
 package sitemap_test
 
 import (
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/triabokon/goscout/internal/sitemap"
 )
 
+const testXMLNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+// testConfig fills zero fields of the config with defaults.
+func testConfig(c sitemap.Config) sitemap.Config {
+	if c.Format == "" {
+		c.Format = sitemap.FormatStandard
+	}
+	if c.XMLNS == "" {
+		c.XMLNS = testXMLNS
+	}
+	if c.MaxURLs == 0 {
+		c.MaxURLs = sitemap.MaxURLsPerFile
+	}
+	if c.MaxFileSize == 0 {
+		c.MaxFileSize = sitemap.MaxFileSize
+	}
+	return c
+}
+
+func TestSitemap_New(t *testing.T) {
+	testCases := []struct {
+		name             string
+		config           sitemap.Config
+		expectedErrorMsg string
+	}{
+		{
+			name:   "valid config",
+			config: testConfig(sitemap.Config{Rules: []string{"/blog/ daily 0.8", "/about - 0.3"}}),
+		},
+		{
+			name:             "too many urls per file",
+			config:           testConfig(sitemap.Config{MaxURLs: sitemap.MaxURLsPerFile + 1}),
+			expectedErrorMsg: "max urls should be between 1 and 50000",
+		},
+		{
+			name:             "zero max file size",
+			config:           sitemap.Config{Format: sitemap.FormatStandard, MaxURLs: 10},
+			expectedErrorMsg: "max file size should be between 1 and",
+		},
+		{
+			name:             "unknown format",
+			config:           testConfig(sitemap.Config{Format: "json"}),
+			expectedErrorMsg: "unknown sitemap format",
+		},
+		{
+			name:             "unknown change frequency",
+			config:           testConfig(sitemap.Config{ChangeFreq: "sometimes"}),
+			expectedErrorMsg: "unknown change frequency",
+		},
+		{
+			name:             "rule without priority",
+			config:           testConfig(sitemap.Config{Rules: []string{"/blog/ daily"}}),
+			expectedErrorMsg: "should have url regexp, change frequency and priority",
+		},
+		{
+			name:             "rule with invalid regexp",
+			config:           testConfig(sitemap.Config{Rules: []string{"/blog/( daily 0.5"}}),
+			expectedErrorMsg: "failed to compile rule regexp",
+		},
+		{
+			name:             "rule with invalid priority",
+			config:           testConfig(sitemap.Config{Rules: []string{"/blog/ daily 1.5"}}),
+			expectedErrorMsg: "should have priority between 0.0 and 1.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := sitemap.New(tc.config)
+			if tc.expectedErrorMsg == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.expectedErrorMsg)
+		})
+	}
+}
+
 func TestSitemap_GenerateSitemap(t *testing.T) {
-	data := map[string][]string{
-		"https://example.com":        {"https://example.com/child1", "https://example.com/child2"},
-		"https://example.com/child1": {"https://example.com/grandchild1"},
+	lastModified := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	pages := sitemap.PageMap{
+		"https://example.com": {
+			URLs:  []string{"https://example.com/child1", "https://example.com/child2"},
+			Depth: 1,
+		},
+		"https://example.com/child1": {
+			URLs:         []string{"https://example.com/grandchild1"},
+			Depth:        2,
+			LastModified: lastModified,
+			Canonical:    "https://example.com/child1",
+		},
+		"https://example.com/child2":      {Depth: 2, Status: http.StatusOK},
+		"https://example.com/grandchild1": {Depth: 3},
+		"https://example.com/missing":     {Depth: 2, Status: http.StatusNotFound},
+		"https://example.com/noindex":     {Depth: 2, NoIndex: true},
+		"https://example.com/copy":        {Depth: 2, Canonical: "https://example.com/child2"},
 	}
 	rootValue := "https://example.com"
-	expectedSitemap := &sitemap.URL{
-		Loc: "https://example.com",
-		URLs: []*sitemap.URL{
-			{
-				Loc: "https://example.com/child1",
-				URLs: []*sitemap.URL{
-					{
-						Loc: "https://example.com/grandchild1",
+
+	t.Run("standard", func(t *testing.T) {
+		s, err := sitemap.New(testConfig(sitemap.Config{
+			ChangeFreq:    sitemap.ChangeFreqMonthly,
+			DepthPriority: true,
+			Rules:         []string{"/child2$ daily -", "/grandchild weekly 0.9", "/child - 0.2"},
+		}))
+		assert.NoError(t, err)
+		require.NoError(t, s.GenerateSitemap(pages, rootValue))
+
+		urlSet, err := s.URLSet()
+		require.NoError(t, err)
+		assert.Equal(t, &sitemap.URLSet{
+			XMLNS: testXMLNS,
+			URLs: []*sitemap.Entry{
+				{Loc: "https://example.com", ChangeFreq: "monthly", Priority: "1.0"},
+				{Loc: "https://example.com/child1", LastMod: "2023-05-06T07:08:09Z", ChangeFreq: "monthly", Priority: "0.2"},
+				{Loc: "https://example.com/child2", ChangeFreq: "daily", Priority: "0.9"},
+				{Loc: "https://example.com/grandchild1", ChangeFreq: "weekly", Priority: "0.9"},
+			},
+		}, urlSet)
+	})
+
+	t.Run("standard with error pages", func(t *testing.T) {
+		s, err := sitemap.New(testConfig(sitemap.Config{IncludeErrorPages: true}))
+		assert.NoError(t, err)
+		require.NoError(t, s.GenerateSitemap(pages, rootValue))
+		// noindex and non-canonical pages are still omitted
+		urlSet, err := s.URLSet()
+		require.NoError(t, err)
+		assert.Len(t, urlSet.URLs, len(pages)-2)
+	})
+
+	t.Run("tree", func(t *testing.T) {
+		expectedSitemap := &sitemap.URL{
+			Loc: "https://example.com",
+			URLs: []*sitemap.URL{
+				{
+					Loc: "https://example.com/child1",
+					URLs: []*sitemap.URL{
+						{
+							Loc: "https://example.com/grandchild1",
+						},
 					},
 				},
+				{
+					Loc: "https://example.com/child2",
+				},
 			},
-			{
-				Loc: "https://example.com/child2",
-			},
+		}
+
+		s, err := sitemap.New(testConfig(sitemap.Config{Format: sitemap.FormatTree}))
+		assert.NoError(t, err)
+		require.NoError(t, s.GenerateSitemap(pages, rootValue))
+		assert.Equal(t, expectedSitemap, s.Tree().URL)
+	})
+}
+
+func TestSitemap_WriteToFile(t *testing.T) {
+	pages := sitemap.PageMap{
+		"https://example.com":       {URLs: []string{"https://example.com/child"}, Depth: 1},
+		"https://example.com/child": {Depth: 2},
+	}
+
+	for name, partial := range map[string]bool{"complete": false, "partial": true} {
+		t.Run(name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "sitemap.xml")
+			s, err := sitemap.New(testConfig(sitemap.Config{Indent: 1}))
+			assert.NoError(t, err)
+			require.NoError(t, s.GenerateSitemap(pages, "https://example.com"))
+			if partial {
+				s.MarkPartial()
+			}
+			files, err := s.WriteToFile(filename)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{filename}, files)
+			// a single part is written under the sitemap file name
+			assert.NoFileExists(t, filepath.Join(filepath.Dir(filename), "sitemap-1.xml"))
+
+			content, err := os.ReadFile(filename)
+			assert.NoError(t, err)
+			assert.Contains(t, string(content), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+			assert.Contains(t, string(content), "<url>\n  <loc>https://example.com/child</loc>\n </url>")
+			assert.Equal(t, partial, strings.Contains(string(content), "partial sitemap"))
+		})
+	}
+}
+
+func TestSitemap_WriteToFileSplit(t *testing.T) {
+	pages := make(sitemap.PageMap)
+	for i := 0; i < 5; i++ {
+		pages[fmt.Sprintf("https://example.com/page%d", i)] = sitemap.Page{
+			Depth:        2,
+			LastModified: time.Date(2023, 5, i+1, 0, 0, 0, 0, time.UTC),
+		}
+	}
+	entrySize := len("\n <url>\n  <loc>https://example.com/page0</loc>\n  <lastmod>2023-05-01T00:00:00Z</lastmod>\n </url>")
+	overhead := len(xml.Header) + len(`<urlset xmlns="`+testXMLNS+`">`) + len("\n</urlset>")
+
+	testCases := []struct {
+		name          string
+		config        sitemap.Config
+		expectedParts [][]string
+		expectedError string
+	}{
+		{
+			name:          "split by urls count",
+			config:        testConfig(sitemap.Config{MaxURLs: 2}),
+			expectedParts: [][]string{{"page0", "page1"}, {"page2", "page3"}, {"page4"}},
+		},
+		{
+			name:          "split by file size",
+			config:        testConfig(sitemap.Config{MaxFileSize: overhead + 3*entrySize}),
+			expectedParts: [][]string{{"page0", "page1", "page2"}, {"page3", "page4"}},
+		},
+		{
+			name:          "gzip parts",
+			config:        testConfig(sitemap.Config{MaxURLs: 3, Gzip: true}),
+			expectedParts: [][]string{{"page0", "page1", "page2"}, {"page3", "page4"}},
 		},
+		{
+			name:          "entry exceeds file size",
+			config:        testConfig(sitemap.Config{MaxFileSize: overhead + entrySize - 1}),
+			expectedError: "exceeds max file size",
+		},
+		{
+			name:          "no base url",
+			config:        testConfig(sitemap.Config{MaxURLs: 2, BaseURL: "-"}),
+			expectedError: "base url is required",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := filepath.Join(dir, "sitemap.xml")
+			tc.config.Indent = 1
+			tc.config.DepthPriority = false
+			switch tc.config.BaseURL {
+			case "-":
+				tc.config.BaseURL = ""
+			case "":
+				tc.config.BaseURL = "https://example.com/sitemaps/"
+			}
+			s, err := sitemap.New(tc.config)
+			require.NoError(t, err)
+			require.NoError(t, s.GenerateSitemap(pages, "https://example.com"))
+
+			files, err := s.WriteToFile(filename)
+			if tc.expectedError != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tc.expectedError)
+				// parts written before the error are removed
+				written, rErr := os.ReadDir(dir)
+				require.NoError(t, rErr)
+				assert.Empty(t, written)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, files, len(tc.expectedParts)+1)
+			assert.Equal(t, filename, files[len(files)-1])
+
+			var index sitemap.Index
+			require.NoError(t, xml.Unmarshal(readFile(t, filename, false), &index))
+			require.Len(t, index.Sitemaps, len(tc.expectedParts))
+			for i, expected := range tc.expectedParts {
+				partName := fmt.Sprintf("sitemap-%d.xml", i+1)
+				if tc.config.Gzip {
+					partName += ".gz"
+				}
+				assert.Equal(t, filepath.Join(dir, partName), files[i])
+				assert.Equal(t, "https://example.com/sitemaps/"+partName, index.Sitemaps[i].Loc)
+
+				content := readFile(t, files[i], tc.config.Gzip)
+				assert.LessOrEqual(t, len(content), tc.config.MaxFileSize)
+				var urlSet sitemap.URLSet
+				require.NoError(t, xml.Unmarshal(content, &urlSet))
+				locs := make([]string, 0, len(urlSet.URLs))
+				for _, e := range urlSet.URLs {
+					locs = append(locs, strings.TrimPrefix(e.Loc, "https://example.com/"))
+				}
+				assert.Equal(t, expected, locs)
+				assert.Equal(t, urlSet.URLs[len(urlSet.URLs)-1].LastMod, index.Sitemaps[i].LastMod)
+			}
+		})
 	}
+}
 
-	s := sitemap.New(sitemap.Config{})
-	s.GenerateSitemap(data, rootValue)
-	assert.Equal(t, expectedSitemap, s.Index().URL)
+func readFile(t *testing.T, filename string, compressed bool) []byte {
+	t.Helper()
+	file, err := os.Open(filename)
+	require.NoError(t, err)
+	defer file.Close()
+	var r io.Reader = file
+	if compressed {
+		zr, err := gzip.NewReader(file)
+		require.NoError(t, err)
+		r = zr
+	}
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return content
 }
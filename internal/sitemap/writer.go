@@ -0,0 +1,331 @@
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	indentSymbol   = " "
+	partialComment = "<!-- partial sitemap: crawl was interrupted before all pages were visited -->\n"
+	gzipExt        = ".gz"
+)
+
+// Index is a sitemap index referencing sitemap part files.
+type Index struct {
+	XMLName  xml.Name      `xml:"sitemapindex"`
+	XMLNS    string        `xml:"xmlns,attr"`
+	Sitemaps []*IndexEntry `xml:"sitemap"`
+}
+
+type IndexEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// WriteToFile writes the xml site map to a file with filename and returns names of written files.
+// Standard sitemap exceeding configured limits is split into numbered part files
+// next to filename, and filename becomes a sitemap index referencing them.
+// Gzipped sitemap files get .gz extension, the index is not compressed.
+func (s *SiteMap) WriteToFile(filename string) ([]string, error) {
+	if s.config.Format == FormatTree {
+		err := s.writeFile(filename, false, func(w io.Writer) error {
+			return s.writeDocument(w, s.tree)
+		})
+		return []string{filename}, err
+	}
+
+	// entries are written to part files as they are generated, so the whole sitemap is never kept in memory
+	pw := s.newPartWriter(filename)
+	err := s.rangeEntries(pw.add)
+	if err == nil {
+		err = pw.finish()
+	}
+	if err != nil {
+		pw.remove()
+		return nil, err
+	}
+	if len(pw.parts) == 1 {
+		name := s.fileName(filename)
+		if err = os.Rename(pw.parts[0].name, name); err != nil {
+			pw.remove()
+			return nil, fmt.Errorf("failed to rename sitemap file: %w", err)
+		}
+		return []string{name}, nil
+	}
+
+	index := &Index{XMLNS: s.config.XMLNS}
+	names := make([]string, 0, len(pw.parts)+1)
+	for _, p := range pw.parts {
+		names = append(names, p.name)
+		index.Sitemaps = append(index.Sitemaps, &IndexEntry{
+			Loc:     strings.TrimSuffix(s.config.BaseURL, "/") + "/" + filepath.Base(p.name),
+			LastMod: p.lastMod,
+		})
+	}
+	if err = s.writeFile(filename, false, func(w io.Writer) error {
+		return s.writeDocument(w, index)
+	}); err != nil {
+		return names, err
+	}
+	return append(names, filename), nil
+}
+
+// part is a written sitemap part file.
+type part struct {
+	name    string
+	lastMod string
+}
+
+// partWriter writes entries of the standard sitemap to numbered part files,
+// the current part is flushed once the next entry would exceed the url or size limit.
+type partWriter struct {
+	s        *SiteMap
+	filename string
+	// overhead is the size of a part without entries
+	overhead int
+	parts    []*part
+
+	current *part
+	file    *fileWriter
+	count   int
+	size    int
+}
+
+func (s *SiteMap) newPartWriter(filename string) *partWriter {
+	overhead := len(xml.Header) + len(s.urlSetStart()) + len(s.urlSetEnd())
+	if s.partial {
+		overhead += len(partialComment)
+	}
+	return &partWriter{s: s, filename: filename, overhead: overhead}
+}
+
+// add writes the entry to the current part, starting the next part if the entry does not fit.
+func (p *partWriter) add(e *Entry) error {
+	encoded, err := p.s.encodeEntry(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sitemap entry: %w", err)
+	}
+	size := len(encoded) + 1
+	if p.overhead+size > p.s.config.MaxFileSize {
+		return fmt.Errorf("sitemap entry %s exceeds max file size", e.Loc)
+	}
+	if p.file != nil && (p.count == p.s.config.MaxURLs || p.size+size > p.s.config.MaxFileSize) {
+		if err = p.close(); err != nil {
+			return err
+		}
+	}
+	if p.file == nil {
+		if err = p.start(); err != nil {
+			return err
+		}
+	}
+	// entries are formatted the same way as xml.MarshalIndent does
+	if p.s.config.Indent > 0 {
+		if _, err = io.WriteString(p.file, "\n"); err != nil {
+			return fmt.Errorf("failed to write %s: %w", p.current.name, err)
+		}
+	}
+	if _, err = p.file.Write(encoded); err != nil {
+		return fmt.Errorf("failed to write %s: %w", p.current.name, err)
+	}
+	p.count++
+	p.size += size
+	// entries are formatted in UTC, so the latest one is the greatest string
+	if e.LastMod > p.current.lastMod {
+		p.current.lastMod = e.LastMod
+	}
+	return nil
+}
+
+// finish closes the last part, a sitemap without entries is written as a single empty part.
+func (p *partWriter) finish() error {
+	if p.file == nil && len(p.parts) == 0 {
+		if err := p.start(); err != nil {
+			return err
+		}
+	}
+	if p.file == nil {
+		return nil
+	}
+	return p.close()
+}
+
+func (p *partWriter) start() error {
+	if len(p.parts) > 0 && p.s.config.BaseURL == "" {
+		return errors.New("base url is required to write sitemap index")
+	}
+	p.current = &part{name: p.s.fileName(partName(p.filename, len(p.parts)+1))}
+	file, err := createFile(p.current.name, p.s.config.Gzip)
+	if err != nil {
+		return err
+	}
+	p.file, p.count, p.size = file, 0, p.overhead
+	if err = p.s.writeHeader(p.file); err != nil {
+		return fmt.Errorf("failed to write %s: %w", p.current.name, err)
+	}
+	if _, err = io.WriteString(p.file, p.s.urlSetStart()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", p.current.name, err)
+	}
+	return nil
+}
+
+func (p *partWriter) close() error {
+	_, err := io.WriteString(p.file, p.s.urlSetEnd())
+	if err != nil {
+		err = fmt.Errorf("failed to write %s: %w", p.current.name, err)
+	}
+	if cErr := p.file.Close(); cErr != nil && err == nil {
+		err = fmt.Errorf("failed to write %s: %w", p.current.name, cErr)
+	}
+	p.file = nil
+	p.parts = append(p.parts, p.current)
+	return err
+}
+
+// remove removes part files written so far, so a failed sitemap does not leave incomplete files.
+func (p *partWriter) remove() {
+	if p.file != nil {
+		_ = p.file.Close()
+		_ = os.Remove(p.current.name)
+		p.file = nil
+	}
+	for _, written := range p.parts {
+		_ = os.Remove(written.name)
+	}
+}
+
+func (s *SiteMap) encodeEntry(e *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	indent := strings.Repeat(indentSymbol, s.config.Indent)
+	enc := xml.NewEncoder(&buf)
+	enc.Indent(indent, indent)
+	if err := enc.EncodeElement(e, xml.StartElement{Name: xml.Name{Local: "url"}}); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *SiteMap) urlSetStart() string {
+	var buf bytes.Buffer
+	buf.WriteString(`<urlset xmlns="`)
+	_ = xml.EscapeText(&buf, []byte(s.config.XMLNS))
+	buf.WriteString(`">`)
+	return buf.String()
+}
+
+func (s *SiteMap) urlSetEnd() string {
+	if s.config.Indent > 0 {
+		return "\n</urlset>"
+	}
+	return "</urlset>"
+}
+
+func (s *SiteMap) writeDocument(w io.Writer, document interface{}) error {
+	if err := s.writeHeader(w); err != nil {
+		return err
+	}
+	xmlSitemap, err := xml.MarshalIndent(document, "", strings.Repeat(indentSymbol, s.config.Indent))
+	if err != nil {
+		return fmt.Errorf("failed to marshal sitemap: %w", err)
+	}
+	if _, err = w.Write(xmlSitemap); err != nil {
+		return fmt.Errorf("failed to write sitemap: %w", err)
+	}
+	return nil
+}
+
+func (s *SiteMap) writeHeader(w io.Writer) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write xml header: %w", err)
+	}
+	if s.partial {
+		if _, err := io.WriteString(w, partialComment); err != nil {
+			return fmt.Errorf("failed to write partial comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeFile creates file with filename and writes its content with write, compressing it if compress is set.
+func (s *SiteMap) writeFile(filename string, compress bool, write func(w io.Writer) error) error {
+	file, err := createFile(filename, compress)
+	if err != nil {
+		return err
+	}
+	if err = write(file); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return nil
+}
+
+// fileWriter is a buffered writer of a file, compressing its content if needed.
+type fileWriter struct {
+	io.Writer
+	file *os.File
+	buf  *bufio.Writer
+	zw   *gzip.Writer
+}
+
+func createFile(filename string, compress bool) (*fileWriter, error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	f := &fileWriter{file: file, buf: bufio.NewWriter(file)}
+	f.Writer = f.buf
+	if compress {
+		f.zw = gzip.NewWriter(f.buf)
+		f.Writer = f.zw
+	}
+	return f, nil
+}
+
+// Close flushes the buffered content and closes the file.
+func (f *fileWriter) Close() error {
+	var err error
+	if f.zw != nil {
+		if zErr := f.zw.Close(); zErr != nil {
+			err = fmt.Errorf("failed to compress file: %w", zErr)
+		}
+	}
+	if err == nil {
+		if fErr := f.buf.Flush(); fErr != nil {
+			err = fmt.Errorf("failed to flush file: %w", fErr)
+		}
+	}
+	if cErr := f.file.Close(); cErr != nil && err == nil {
+		err = fmt.Errorf("failed to close file: %w", cErr)
+	}
+	return err
+}
+
+// fileName returns name of the sitemap file, adding gzip extension if needed.
+func (s *SiteMap) fileName(filename string) string {
+	if s.config.Gzip && !strings.HasSuffix(filename, gzipExt) {
+		return filename + gzipExt
+	}
+	return filename
+}
+
+// partName returns name of the numbered sitemap part, e.g. sitemap-1.xml for sitemap.xml.
+func partName(filename string, n int) string {
+	filename = strings.TrimSuffix(filename, gzipExt)
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, ext), n, ext)
+}
@@ -0,0 +1,217 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/triabokon/goscout/internal/boltdb"
+	"github.com/triabokon/goscout/internal/crawler"
+)
+
+var ErrNoConfig = fmt.Errorf("state has no saved config")
+
+const (
+	fileName = "state.db"
+
+	bucketMeta     = "meta"
+	bucketFrontier = "frontier"
+	bucketPages    = "pages"
+	bucketSkipped  = "skipped"
+	bucketErrors   = "errors"
+
+	keyConfig = "config"
+)
+
+// buckets are all buckets of the state database.
+func buckets() []string {
+	return []string{bucketMeta, bucketFrontier, bucketPages, bucketSkipped, bucketErrors}
+}
+
+// Store keeps crawl state in an embedded database in the state directory.
+type Store struct {
+	db *bbolt.DB
+}
+
+// Open opens the state database in the directory, creating it if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	db, err := boltdb.Open(filepath.Join(dir, fileName), buckets()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Reset removes the state of the previous crawl, so a new crawl does not mix its pages with pages of another one.
+func (s *Store) Reset() error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		for _, b := range buckets() {
+			if _, err := recreateBucket(tx, b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset state: %w", err)
+	}
+	return nil
+}
+
+// SaveConfig saves the crawl config, so the crawl could be resumed with the same settings.
+func (s *Store) SaveConfig(config interface{}) error {
+	value, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(bucketMeta)).Put([]byte(keyConfig), value)
+	})
+}
+
+// LoadConfig loads previously saved crawl config into the config.
+func (s *Store) LoadConfig(config interface{}) error {
+	return s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket([]byte(bucketMeta)).Get([]byte(keyConfig))
+		if value == nil {
+			return ErrNoConfig
+		}
+		if err := json.Unmarshal(value, config); err != nil {
+			return fmt.Errorf("failed to unmarshal config: %w", err)
+		}
+		return nil
+	})
+}
+
+// Save writes the crawl state in a single transaction, the frontier and errors replace previous ones,
+// while pages and skipped urls are changes that are applied to previously saved ones.
+func (s *Store) Save(state *crawler.State) error {
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		frontier, err := recreateBucket(tx, bucketFrontier)
+		if err != nil {
+			return err
+		}
+		for _, j := range state.Frontier {
+			if pErr := putJSON(frontier, j.URL, j); pErr != nil {
+				return pErr
+			}
+		}
+		pages := tx.Bucket([]byte(bucketPages))
+		for u, page := range state.Results {
+			if pErr := putJSON(pages, u, page); pErr != nil {
+				return pErr
+			}
+		}
+		skipped := tx.Bucket([]byte(bucketSkipped))
+		for u, reason := range state.Skipped {
+			// the url is not skipped anymore, since it was crawled after all
+			if reason == "" {
+				if dErr := skipped.Delete([]byte(u)); dErr != nil {
+					return fmt.Errorf("failed to delete skipped url: %w", dErr)
+				}
+				continue
+			}
+			if pErr := skipped.Put([]byte(u), []byte(reason)); pErr != nil {
+				return fmt.Errorf("failed to put skipped url: %w", pErr)
+			}
+		}
+		errs, err := recreateBucket(tx, bucketErrors)
+		if err != nil {
+			return err
+		}
+		for i, e := range state.Errors {
+			// keys are zero-padded to keep errors order
+			if pErr := errs.Put([]byte(fmt.Sprintf("%010d", i)), []byte(e)); pErr != nil {
+				return fmt.Errorf("failed to put error: %w", pErr)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// Load reads the last saved crawl state, its pages are put to the result set instead of the state,
+// so they are not kept in memory.
+func (s *Store) Load(results crawler.ResultSet) (*crawler.State, error) {
+	state := &crawler.State{
+		Results: make(map[string]crawler.PageResult),
+		Skipped: make(map[string]crawler.SkipReason),
+	}
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		err := tx.Bucket([]byte(bucketFrontier)).ForEach(func(_, v []byte) error {
+			var j crawler.Job
+			if uErr := json.Unmarshal(v, &j); uErr != nil {
+				return fmt.Errorf("failed to unmarshal job: %w", uErr)
+			}
+			state.Frontier = append(state.Frontier, j)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket([]byte(bucketPages)).ForEach(func(_, v []byte) error {
+			var page crawler.PageResult
+			if uErr := json.Unmarshal(v, &page); uErr != nil {
+				return fmt.Errorf("failed to unmarshal page: %w", uErr)
+			}
+			if pErr := results.Put(page); pErr != nil {
+				return fmt.Errorf("failed to put page: %w", pErr)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket([]byte(bucketSkipped)).ForEach(func(k, v []byte) error {
+			state.Skipped[string(k)] = crawler.SkipReason(v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(bucketErrors)).ForEach(func(_, v []byte) error {
+			state.Errors = append(state.Errors, string(v))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state: %w", err)
+	}
+	return state, nil
+}
+
+// recreateBucket removes all values from the bucket.
+func recreateBucket(tx *bbolt.Tx, name string) (*bbolt.Bucket, error) {
+	if err := tx.DeleteBucket([]byte(name)); err != nil {
+		return nil, fmt.Errorf("failed to delete bucket %s: %w", name, err)
+	}
+	b, err := tx.CreateBucket([]byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bucket %s: %w", name, err)
+	}
+	return b, nil
+}
+
+func putJSON(b *bbolt.Bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+	if err = b.Put([]byte(key), data); err != nil {
+		return fmt.Errorf("failed to put value: %w", err)
+	}
+	return nil
+}
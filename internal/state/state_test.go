@@ -0,0 +1,140 @@
+package state_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triabokon/goscout/internal/crawler"
+	"github.com/triabokon/goscout/internal/results"
+	"github.com/triabokon/goscout/internal/state"
+)
+
+// loadPages loads the state, returning its pages by their urls separately.
+func loadPages(t *testing.T, s *state.Store) (*crawler.State, map[string]crawler.PageResult) {
+	pageResults := results.NewMemory()
+	loaded, err := s.Load(pageResults)
+	assert.NoError(t, err)
+	pages := make(map[string]crawler.PageResult)
+	assert.NoError(t, pageResults.Range(func(r crawler.PageResult) error {
+		pages[r.URL] = r
+		return nil
+	}))
+	return loaded, pages
+}
+
+func TestStore_SaveLoad(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := state.Open(dir)
+	assert.NoError(t, err)
+
+	first := &crawler.State{
+		Frontier: []crawler.Job{{URL: "https://example.com/a", Depth: 2}},
+		Results: map[string]crawler.PageResult{
+			"https://example.com": {URL: "https://example.com", URLs: []string{"https://example.com/a"}, Depth: 1},
+		},
+		Skipped: map[string]crawler.SkipReason{"https://example.com/admin": crawler.SkipReasonRobotsDisallowed},
+		Errors:  []string{"first error"},
+	}
+	assert.NoError(t, s.Save(first))
+
+	// the second state has only changes since the first one
+	second := &crawler.State{
+		Frontier: []crawler.Job{{URL: "https://example.com/b", Depth: 3}},
+		Results: map[string]crawler.PageResult{
+			"https://example.com/a": {
+				URL:          "https://example.com/a",
+				FinalURL:     "https://example.com/a/",
+				Status:       200,
+				ContentType:  "text/html",
+				Size:         1024,
+				Latency:      150 * time.Millisecond,
+				Depth:        2,
+				Parent:       "https://example.com",
+				FetchedAt:    time.Date(2023, 1, 2, 3, 4, 6, 0, time.UTC),
+				Attempts:     2,
+				URLs:         []string{"https://example.com/b"},
+				LastModified: time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+			"https://example.com/c": {URL: "https://example.com/c", Depth: 2, Status: 404, Error: "not found"},
+		},
+		Skipped: map[string]crawler.SkipReason{
+			"https://example.com/admin": "",
+			"https://example.com/d":     crawler.SkipReasonNoFollow,
+		},
+		Errors: []string{"first error", "second error"},
+	}
+	assert.NoError(t, s.Save(second))
+	assert.NoError(t, s.Close())
+
+	// state should survive reopening
+	s, err = state.Open(dir)
+	assert.NoError(t, err)
+	defer s.Close()
+
+	loaded, pages := loadPages(t, s)
+	assert.Equal(t, &crawler.State{
+		Frontier: second.Frontier,
+		Results:  map[string]crawler.PageResult{},
+		Skipped:  map[string]crawler.SkipReason{"https://example.com/d": crawler.SkipReasonNoFollow},
+		Errors:   second.Errors,
+	}, loaded)
+	assert.Equal(t, map[string]crawler.PageResult{
+		"https://example.com":   first.Results["https://example.com"],
+		"https://example.com/a": second.Results["https://example.com/a"],
+		"https://example.com/c": second.Results["https://example.com/c"],
+	}, pages)
+}
+
+func TestStore_Config(t *testing.T) {
+	type config struct {
+		SiteURL string
+		Depth   int
+	}
+
+	s, err := state.Open(t.TempDir())
+	assert.NoError(t, err)
+	defer s.Close()
+
+	var loaded config
+	assert.ErrorIs(t, s.LoadConfig(&loaded), state.ErrNoConfig)
+
+	saved := config{SiteURL: "https://example.com", Depth: 3}
+	assert.NoError(t, s.SaveConfig(&saved))
+	assert.NoError(t, s.LoadConfig(&loaded))
+	assert.Equal(t, saved, loaded)
+}
+
+func TestStore_Reset(t *testing.T) {
+	s, err := state.Open(t.TempDir())
+	assert.NoError(t, err)
+	defer s.Close()
+
+	previous := &crawler.State{
+		Frontier: []crawler.Job{{URL: "https://old.example.com/a", Depth: 2}},
+		Results: map[string]crawler.PageResult{
+			"https://old.example.com": {URL: "https://old.example.com", Depth: 1},
+		},
+		Skipped: map[string]crawler.SkipReason{"https://old.example.com/admin": crawler.SkipReasonRobotsDisallowed},
+		Errors:  []string{"old error"},
+	}
+	assert.NoError(t, s.Save(previous))
+	assert.NoError(t, s.SaveConfig(map[string]string{"SiteURL": "https://old.example.com"}))
+	assert.NoError(t, s.Reset())
+
+	// pages of the previous crawl are not mixed with pages of the new one
+	current := &crawler.State{
+		Results: map[string]crawler.PageResult{"https://example.com": {URL: "https://example.com", Depth: 1}},
+		Skipped: map[string]crawler.SkipReason{},
+	}
+	assert.NoError(t, s.Save(current))
+	loaded, pages := loadPages(t, s)
+	assert.Empty(t, loaded.Frontier)
+	assert.Empty(t, loaded.Skipped)
+	assert.Empty(t, loaded.Errors)
+	assert.Equal(t, current.Results, pages)
+	var config map[string]string
+	assert.ErrorIs(t, s.LoadConfig(&config), state.ErrNoConfig)
+}
@@ -0,0 +1,54 @@
+package throttle
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/triabokon/goscout/flags"
+)
+
+type Config struct {
+	// RequestsPerSecond limits the request rate to a single host, 0 disables the limit
+	RequestsPerSecond float64
+	// MaxInFlight limits concurrent requests to a single host, 0 disables the limit
+	MaxInFlight int
+
+	// Adaptive slows down requests to a host when its responses get slow or fail
+	Adaptive           bool
+	LatencyThreshold   time.Duration
+	ErrorRateThreshold float64
+	MaxSlowdown        float64
+}
+
+func (c *Config) Flags(prefix string) *pflag.FlagSet {
+	const name = "ThrottleConfig"
+	f := pflag.NewFlagSet(name, pflag.PanicOnError)
+
+	f.Float64Var(
+		&c.RequestsPerSecond, "requests_per_second",
+		5, "maximum number of requests per second to a single host, 0 disables the limit",
+	)
+	f.IntVar(
+		&c.MaxInFlight, "max_in_flight",
+		4, "maximum number of concurrent requests to a single host, 0 disables the limit",
+	)
+	f.BoolVar(
+		&c.Adaptive, "adaptive",
+		true, "slow down requests to a host when its response latency or error rate exceeds thresholds",
+	)
+	f.DurationVar(
+		&c.LatencyThreshold, "latency_threshold",
+		2*time.Second, "average response latency of a host that triggers slowdown, 0 disables it",
+	)
+	f.Float64Var(
+		&c.ErrorRateThreshold, "error_rate_threshold",
+		0.1, "share of failed and 5xx responses of a host that triggers slowdown, 0 disables it",
+	)
+	f.Float64Var(
+		&c.MaxSlowdown, "max_slowdown",
+		8, "maximum factor the request interval of a host is multiplied by when slowing down",
+	)
+
+	return flags.MapWithPrefix(f, name, pflag.PanicOnError, prefix)
+}
@@ -0,0 +1,188 @@
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// smoothing is the weight of the latest response in the average latency and error rate of a host.
+	smoothing = 0.2
+	// recovery is the factor the slowdown is reduced by after a response, until it meets the host load.
+	recovery = 0.9
+)
+
+//go:generate mockgen -destination=./mocks/http_mock.go -package=mocks github.com/triabokon/goscout/internal/throttle HTTPClient
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Throttler is an http client that limits request rate and concurrency per host.
+type Throttler struct {
+	config Config
+	client HTTPClient
+
+	mu    sync.Mutex
+	hosts map[string]*host
+}
+
+// host keeps the request schedule and response statistics of a single host.
+type host struct {
+	// slots limits requests in flight, it is nil when there is no limit
+	slots chan struct{}
+
+	mu        sync.Mutex
+	next      time.Time
+	slowdown  float64
+	latency   time.Duration
+	errorRate float64
+}
+
+func New(c Config, client HTTPClient) *Throttler {
+	return &Throttler{
+		config: c,
+		client: client,
+		hosts:  make(map[string]*host),
+	}
+}
+
+// Do waits for a free request slot of the request host and sends the request.
+// The slot is held until the response body is closed.
+func (t *Throttler) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	h := t.host(req.URL.Scheme + "://" + req.URL.Host)
+	if h.slots != nil {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to wait for request slot: %w", ctx.Err())
+		case h.slots <- struct{}{}:
+		}
+	}
+	if err := t.wait(ctx, h); err != nil {
+		h.release()
+		return nil, fmt.Errorf("failed to wait for request rate limit: %w", err)
+	}
+
+	started := time.Now()
+	resp, err := t.client.Do(req)
+	t.record(h, time.Since(started), err != nil || resp.StatusCode >= http.StatusInternalServerError)
+	if err != nil {
+		h.release()
+		return nil, err
+	}
+	resp.Body = &body{ReadCloser: resp.Body, release: h.release}
+	return resp, nil
+}
+
+// Slowdown returns the current slowdown factors of hosts that are slowed down.
+func (t *Throttler) Slowdown() map[string]float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	result := make(map[string]float64)
+	for origin, h := range t.hosts {
+		h.mu.Lock()
+		if h.slowdown > 1 {
+			result[origin] = h.slowdown
+		}
+		h.mu.Unlock()
+	}
+	return result
+}
+
+func (t *Throttler) host(origin string) *host {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	h, ok := t.hosts[origin]
+	if !ok {
+		h = &host{slowdown: 1}
+		if t.config.MaxInFlight > 0 {
+			h.slots = make(chan struct{}, t.config.MaxInFlight)
+		}
+		t.hosts[origin] = h
+	}
+	return h
+}
+
+// wait reserves the next request slot of the host, so requests are spread by the slowed down interval.
+func (t *Throttler) wait(ctx context.Context, h *host) error {
+	if t.config.RequestsPerSecond <= 0 {
+		return nil
+	}
+	h.mu.Lock()
+	now := time.Now()
+	if h.next.Before(now) {
+		h.next = now
+	}
+	wait := h.next.Sub(now)
+	interval := time.Duration(float64(time.Second) / t.config.RequestsPerSecond * h.slowdown)
+	h.next = h.next.Add(interval)
+	h.mu.Unlock()
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// record updates response statistics of the host and adapts its slowdown.
+func (t *Throttler) record(h *host, latency time.Duration, failed bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	failure := 0.0
+	if failed {
+		failure = 1
+	}
+	if h.latency == 0 {
+		h.latency = latency
+	} else {
+		h.latency = time.Duration(smoothing*float64(latency) + (1-smoothing)*float64(h.latency))
+	}
+	h.errorRate = smoothing*failure + (1-smoothing)*h.errorRate
+	if !t.config.Adaptive {
+		return
+	}
+	// the slowdown follows how many times the host is over its thresholds, so a single failure
+	// slows it down a little, and the slowdown decreases gradually once the host is less loaded
+	load := 1.0
+	if t.config.LatencyThreshold > 0 {
+		load = math.Max(load, float64(h.latency)/float64(t.config.LatencyThreshold))
+	}
+	if t.config.ErrorRateThreshold > 0 {
+		load = math.Max(load, h.errorRate/t.config.ErrorRateThreshold)
+	}
+	h.slowdown = math.Min(math.Max(h.slowdown*recovery, load), t.config.MaxSlowdown)
+	if h.slowdown < 1 {
+		h.slowdown = 1
+	}
+}
+
+func (h *host) release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+// body releases the request slot of the host once the response body is closed.
+type body struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *body) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
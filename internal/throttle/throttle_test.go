@@ -0,0 +1,135 @@
+package throttle_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triabokon/goscout/internal/throttle"
+	"github.com/triabokon/goscout/internal/throttle/mocks"
+)
+
+func newRequest(t *testing.T, ctx context.Context, u string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	assert.NoError(t, err)
+	return req
+}
+
+func newResponse(status int) *http.Response {
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("body"))}
+}
+
+func TestThrottler_MaxInFlight(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	httpClient := mocks.NewMockHTTPClient(ctrl)
+	throttler := throttle.New(throttle.Config{MaxInFlight: 2}, httpClient)
+
+	var inFlight, maxInFlight int64
+	httpClient.EXPECT().Do(gomock.Any()).DoAndReturn(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "example.com" {
+			n := atomic.AddInt64(&inFlight, 1)
+			for {
+				m := atomic.LoadInt64(&maxInFlight)
+				if n <= m || atomic.CompareAndSwapInt64(&maxInFlight, m, n) {
+					break
+				}
+			}
+		}
+		return newResponse(http.StatusOK), nil
+	}).Times(7)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := throttler.Do(newRequest(t, context.Background(), "https://example.com/page"))
+			assert.NoError(t, err)
+			// the slot is held until the body is read and closed
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt64(&inFlight, -1)
+			assert.NoError(t, resp.Body.Close())
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(2), maxInFlight)
+
+	// hosts have separate limits
+	resp, err := throttler.Do(newRequest(t, context.Background(), "https://other.com/page"))
+	assert.NoError(t, err)
+	assert.NoError(t, resp.Body.Close())
+}
+
+func TestThrottler_RequestsPerSecond(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	httpClient := mocks.NewMockHTTPClient(ctrl)
+	throttler := throttle.New(throttle.Config{RequestsPerSecond: 100}, httpClient)
+	httpClient.EXPECT().Do(gomock.Any()).Return(newResponse(http.StatusOK), nil).Times(5)
+
+	started := time.Now()
+	for i := 0; i < 5; i++ {
+		resp, err := throttler.Do(newRequest(t, context.Background(), "https://example.com/page"))
+		assert.NoError(t, err)
+		assert.NoError(t, resp.Body.Close())
+	}
+	assert.GreaterOrEqual(t, time.Since(started), 40*time.Millisecond)
+}
+
+func TestThrottler_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	httpClient := mocks.NewMockHTTPClient(ctrl)
+	throttler := throttle.New(throttle.Config{MaxInFlight: 1, RequestsPerSecond: 1}, httpClient)
+
+	// failed request releases its slot
+	httpClient.EXPECT().Do(gomock.Any()).Return(nil, errors.New("connection refused"))
+	_, err := throttler.Do(newRequest(t, context.Background(), "https://example.com/page"))
+	assert.EqualError(t, err, "connection refused")
+
+	// the next request has to wait a second, so it is cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = throttler.Do(newRequest(t, ctx, "https://example.com/page"))
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestThrottler_Slowdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	httpClient := mocks.NewMockHTTPClient(ctrl)
+	throttler := throttle.New(throttle.Config{
+		RequestsPerSecond:  1000,
+		Adaptive:           true,
+		ErrorRateThreshold: 0.1,
+		MaxSlowdown:        8,
+	}, httpClient)
+	send := func(status, times int) {
+		for i := 0; i < times; i++ {
+			httpClient.EXPECT().Do(gomock.Any()).Return(newResponse(status), nil)
+			resp, err := throttler.Do(newRequest(t, context.Background(), "https://example.com/page"))
+			assert.NoError(t, err)
+			assert.NoError(t, resp.Body.Close())
+		}
+	}
+
+	// a single failure puts the error rate twice over the threshold
+	send(http.StatusServiceUnavailable, 1)
+	assert.InDelta(t, 2, throttler.Slowdown()["https://example.com"], 0.01)
+	send(http.StatusOK, 1)
+	assert.InDelta(t, 1.8, throttler.Slowdown()["https://example.com"], 0.01)
+
+	// the slowdown of a failing host is limited
+	send(http.StatusServiceUnavailable, 10)
+	assert.Equal(t, map[string]float64{"https://example.com": 8}, throttler.Slowdown())
+
+	send(http.StatusOK, 30)
+	assert.Empty(t, throttler.Slowdown())
+}